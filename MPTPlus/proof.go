@@ -0,0 +1,63 @@
+package MPTPlus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/crypto"
+)
+
+// 从根节点到叶子节点路径上每个节点的数据, 只知道Root的一方可以用它验证key和value在树中
+type Proof []types.HexBytes
+
+func (mtp *MTP) GetProof(key []byte) (Proof, error) {
+	parentHashes, prefixs, err := mtp.FindParents(key)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(bytes.Join(prefixs, nil), key) {
+		return nil, errors.New("key not exist")
+	}
+	proof := make(Proof, 0, len(parentHashes)+1)
+	for _, hash := range append([][]byte{mtp.Root}, parentHashes...) {
+		data, err := mtp.DB.Get(hash)
+		if err != nil {
+			return nil, err
+		}
+		proof = append(proof, data)
+	}
+	return proof, nil
+}
+
+// 每个节点的hash都要出现在上一个节点的Sons中, 路径拼接起来等于key, 叶子节点指向value的hash
+func VerifyProof(root, key, value []byte, proof Proof) bool {
+	if len(proof) < 2 || !bytes.Equal(crypto.Sha3_256(proof[0]), root) {
+		return false
+	}
+	var parent TrieNode
+	if json.Unmarshal(proof[0], &parent) != nil {
+		return false
+	}
+	path := make([]byte, 0, len(key))
+	for _, data := range proof[1:] {
+		var node TrieNode
+		if json.Unmarshal(data, &node) != nil || !parent.hasSon(crypto.Sha3_256(data), node.PathValue) {
+			return false
+		}
+		path = append(path, node.PathValue...)
+		parent = node
+	}
+	return parent.Leaf && len(parent.Sons) == 1 && bytes.Equal(path, key) &&
+		bytes.Equal(parent.Sons[0].Hash, crypto.Sha3_256(value))
+}
+
+func (node TrieNode) hasSon(hash, pathValue []byte) bool {
+	for _, son := range node.Sons {
+		if bytes.Equal(son.Hash, hash) && bytes.Equal(son.PathValue, pathValue) {
+			return true
+		}
+	}
+	return false
+}
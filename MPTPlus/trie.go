@@ -215,3 +215,34 @@ func PrefixLength(a, b []byte) int {
 	}
 	return i
 }
+
+// 按照key从小到大的顺序遍历树中所有的key和value, fn返回false时停止遍历
+func (mtp *MTP) Iterate(fn func(key, value []byte) bool) error {
+	_, err := mtp.iterate(mtp.Root, nil, fn)
+	return err
+}
+
+func (mtp *MTP) iterate(hash, prefix []byte, fn func(key, value []byte) bool) (bool, error) {
+	node, err := mtp.GetNode(hash)
+	if err != nil {
+		return false, err
+	}
+	if node == nil {
+		return false, errors.New("node not exist")
+	}
+	key := append(append([]byte{}, prefix...), node.PathValue...)
+	if node.Leaf {
+		value, err := mtp.DB.Get(node.Sons[0].Hash)
+		if err != nil {
+			return false, err
+		}
+		return fn(key, value), nil
+	}
+	for _, son := range node.Sons {
+		goon, err := mtp.iterate(son.Hash, key, fn)
+		if err != nil || !goon {
+			return goon, err
+		}
+	}
+	return true, nil
+}
@@ -1 +1,68 @@
 package MPTPlus
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/db"
+)
+
+func TestMTP_Iterate(t *testing.T) {
+	mtp := NewMTP(db.NewMemKVDatabase())
+	keys := make([][]byte, 0)
+	for i := 0; i < 50; i++ {
+		key := crypto.Sha3_256([]byte(fmt.Sprint(i)))
+		keys = append(keys, key)
+		if err := mtp.MustInsert(key, []byte(fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+
+	index := 0
+	err := mtp.Iterate(func(key, value []byte) bool {
+		if !bytes.Equal(key, keys[index]) {
+			t.Fatalf("key %d mismatch", index)
+		}
+		v, _ := mtp.GetValue(key)
+		if !bytes.Equal(v, value) {
+			t.Fatalf("value %d mismatch", index)
+		}
+		index++
+		return true
+	})
+	if err != nil || index != len(keys) {
+		t.Fatal("iterate failed", err, index)
+	}
+}
+
+func TestMTP_Proof(t *testing.T) {
+	mtp := NewMTP(db.NewMemKVDatabase())
+	for i := 0; i < 50; i++ {
+		if err := mtp.MustInsert(crypto.Sha3_256([]byte(fmt.Sprint(i))), []byte(fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key := crypto.Sha3_256([]byte("7"))
+	proof, err := mtp.GetProof(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyProof(mtp.Root, key, []byte("7"), proof) {
+		t.Fatal("valid proof rejected")
+	}
+	if VerifyProof(mtp.Root, key, []byte("8"), proof) {
+		t.Fatal("wrong value accepted")
+	}
+	if VerifyProof(mtp.Root, crypto.Sha3_256([]byte("8")), []byte("7"), proof) {
+		t.Fatal("wrong key accepted")
+	}
+	if _, err := mtp.GetProof(crypto.Sha3_256([]byte("50"))); err == nil {
+		t.Fatal("proof of missing key")
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/hex"
+
+	"github.com/EducationEKT/EKT/bancor"
+	"github.com/EducationEKT/EKT/blockchain"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/encapdb"
+
+	"github.com/EducationEKT/xserver/x_err"
+	"github.com/EducationEKT/xserver/x_http/x_req"
+	"github.com/EducationEKT/xserver/x_http/x_resp"
+	"github.com/EducationEKT/xserver/x_http/x_router"
+	"github.com/EducationEKT/xserver/x_utils/x_type"
+)
+
+const (
+	// 一次最多查询的区块数量
+	MaxHistoryBlocks = 1000
+)
+
+func init() {
+	x_router.Get("/bancor/api/info", bancorInfo)
+	x_router.Get("/bancor/api/quote", bancorQuote)
+	x_router.Get("/bancor/api/history", bancorHistory)
+	x_router.Get("/bancor/api/converter", converterInfo)
+	x_router.Get("/bancor/api/convertQuote", converterQuote)
+}
+
+type BancorInfo struct {
+	*bancor.Bancor
+	SpotPrice string `json:"spotPrice"`
+}
+
+func bancorInfo(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	b := mustGetChain(req).LastHeader().GetBancor()
+	return x_resp.Return(BancorInfo{Bancor: b, SpotPrice: bancor.FormatPrice(b.SpotPrice())}, nil)
+}
+
+// side为buy时amount是支付的EKT数量, 为sell时amount是卖出的Gas数量
+func bancorQuote(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	side := req.MustGetString("side")
+	amount, err := types.ParseAmount(req.MustGetString("amount"))
+	if err != nil {
+		return x_resp.Return(nil, err)
+	}
+	return x_resp.Return(mustGetChain(req).LastHeader().GetBancor().Quote(side, amount))
+}
+
+// 查询[from, to]区块范围内的成交记录, 默认为最近的100个区块
+func bancorHistory(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	chain := mustGetChain(req)
+	to := chain.GetLastHeight()
+	if v, exist := req.GetParam("to"); exist {
+		if value, ok := x_type.GetInt64(v); ok && value < to {
+			to = value
+		}
+	}
+	from := to - 99
+	if v, exist := req.GetParam("from"); exist {
+		if value, ok := x_type.GetInt64(v); ok {
+			from = value
+		}
+	}
+	if from < 1 {
+		from = 1
+	}
+	if to-from >= MaxHistoryBlocks {
+		from = to - MaxHistoryBlocks + 1
+	}
+	points := make([]blockchain.BancorPricePoint, 0)
+	for height := from; height <= to; height++ {
+		header := encapdb.GetHeaderByHeight(chain.ChainId, height)
+		if header == nil {
+			continue
+		}
+		blockPoints, err := header.BancorPricePoints()
+		if err != nil {
+			return nil, x_err.New(-1, err.Error())
+		}
+		points = append(points, blockPoints...)
+	}
+	return x_resp.Return(points, nil)
+}
+
+func getConverter(req *x_req.XReq) (*bancor.Converter, error) {
+	address, err := hex.DecodeString(req.MustGetString("address"))
+	if err != nil {
+		return nil, err
+	}
+	return mustGetChain(req).LastHeader().GetConverter(address)
+}
+
+func converterInfo(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	return x_resp.Return(getConverter(req))
+}
+
+// 在一个兑换合约中把amount个from兑换为to的报价
+func converterQuote(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	converter, err := getConverter(req)
+	if err != nil {
+		return x_resp.Return(nil, err)
+	}
+	amount, err := types.ParseAmount(req.MustGetString("amount"))
+	if err != nil {
+		return x_resp.Return(nil, err)
+	}
+	return x_resp.Return(converter.QuoteConvert(req.MustGetString("from"), req.MustGetString("to"), amount))
+}
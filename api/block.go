@@ -21,13 +21,14 @@ func init() {
 	x_router.Get("/block/api/getHeaderByHeight", getHeaderByHeight)
 	x_router.Get("/block/api/getHeaderByHash", getHeaderByHash)
 	x_router.Get("/block/api/getBlockByHeight", getBlockByHeight)
+	x_router.Get("/block/api/limits", blockLimits)
 
 	x_router.Post("/block/api/blockFromPeer", broadcast, blockFromPeer)
 }
 
 func getBlockByHeight(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 	height := req.MustGetInt64("height")
-	block := encapdb.GetBlockByHeight(1, height)
+	block := encapdb.GetBlockByHeight(mustGetChain(req).ChainId, height)
 	if block == nil {
 		return x_resp.Fail(-1, "not found", nil), nil
 	}
@@ -47,16 +48,16 @@ func getHeaderByHash(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 }
 
 func lastBlock(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
-	return x_resp.Return(node.GetMainChain().LastHeader(), nil)
+	return x_resp.Return(mustGetChain(req).LastHeader(), nil)
 }
 
 func getHeaderByHeight(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
-	bc := node.GetMainChain()
+	bc := mustGetChain(req)
 	height := req.MustGetInt64("height")
 	if bc.GetLastHeight() < height {
 		return nil, x_err.New(-404, fmt.Sprintf("Heigth %d is heigher than current height, current height is %d .", height, bc.GetLastHeight()))
 	}
-	return x_resp.Return(node.GetBlockByHeight(1, height), nil)
+	return x_resp.Return(node.GetBlockByHeight(bc.ChainId, height), nil)
 }
 
 func blockFromPeer(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
@@ -67,6 +68,10 @@ func blockFromPeer(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 	if err != nil {
 		return x_resp.Return(nil, err)
 	}
-	node.BlockFromPeer(cLog, &block)
+	node.BlockFromPeer(getChainId(req), cLog, &block)
 	return x_resp.Return("received", nil)
 }
+
+func blockLimits(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	return x_resp.Return(mustGetChain(req).BlockLimits(), nil)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/EducationEKT/EKT/blockchain"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/node"
+	"github.com/EducationEKT/EKT/param"
+
+	"github.com/EducationEKT/xserver/x_err"
+	"github.com/EducationEKT/xserver/x_http/x_req"
+	"github.com/EducationEKT/xserver/x_http/x_resp"
+	"github.com/EducationEKT/xserver/x_http/x_router"
+	"github.com/EducationEKT/xserver/x_utils/x_type"
+)
+
+type ForkStatus struct {
+	param.Fork
+	Active bool `json:"active"`
+}
+
+type ForkSchedule struct {
+	Height        int64        `json:"height"`
+	HeaderVersion int          `json:"headerVersion"`
+	Forks         []ForkStatus `json:"forks"`
+}
+
+// 请求中的chainId, 不传时为主链
+func getChainId(req *x_req.XReq) int64 {
+	if v, exist := req.GetParam("chainId"); exist {
+		if chainId, ok := x_type.GetInt64(v); ok {
+			return chainId
+		}
+	}
+	return param.MainChainId
+}
+
+// 根据chainId参数找到对应的链, 当前节点没有运行这条链时返回错误
+func mustGetChain(req *x_req.XReq) *blockchain.BlockChain {
+	chain := node.GetChain(getChainId(req))
+	if chain == nil {
+		panic(&x_err.LogicalErr{Status: -404, Msg: "chain not found"})
+	}
+	return chain
+}
+
+func init() {
+	x_router.Get("/chain/api/forks", forks)
+	x_router.Get("/chain/api/events", chainEvents)
+	x_router.Get("/chain/api/list", chainList)
+}
+
+type ChainInfo struct {
+	ChainId   int64       `json:"chainId"`
+	Height    int64       `json:"height"`
+	Delegates types.Peers `json:"delegates"`
+}
+
+// 当前节点运行的所有链
+func chainList(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	chains := make([]ChainInfo, 0)
+	for _, chainId := range param.ChainIds() {
+		chain := node.GetChain(chainId)
+		if chain == nil {
+			continue
+		}
+		chains = append(chains, ChainInfo{
+			ChainId:   chainId,
+			Height:    chain.GetLastHeight(),
+			Delegates: param.ChainDelegates(chainId),
+		})
+	}
+	return x_resp.Return(chains, nil)
+}
+
+// 当前网络的升级计划以及在下一个区块高度是否已经激活
+func forks(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	height := mustGetChain(req).GetLastHeight() + 1
+	schedule := ForkSchedule{
+		Height:        height,
+		HeaderVersion: blockchain.HeaderVersion(height),
+		Forks:         make([]ForkStatus, 0, len(param.Forks)),
+	}
+	for _, fork := range param.Forks {
+		schedule.Forks = append(schedule.Forks, ForkStatus{Fork: fork, Active: fork.Height <= height})
+	}
+	return x_resp.Return(schedule, nil)
+}
+
+// 查询指定高度区块中的系统事件, 可以通过type过滤事件类型
+func chainEvents(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	bc := mustGetChain(req)
+	height := req.MustGetInt64("height")
+	if bc.GetLastHeight() < height {
+		return nil, x_err.New(-404, fmt.Sprintf("Heigth %d is heigher than current height, current height is %d .", height, bc.GetLastHeight()))
+	}
+	header := node.GetBlockByHeight(bc.ChainId, height)
+	if header == nil {
+		return nil, x_err.New(-404, fmt.Sprintf("Header at height %d not found.", height))
+	}
+	eventType := ""
+	if v, exist := req.GetParam("type"); exist {
+		eventType = x_type.V2String(v)
+	}
+	return x_resp.Return(header.GetEvents(eventType))
+}
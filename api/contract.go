@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/EducationEKT/EKT/blockchain"
+	"github.com/EducationEKT/EKT/node"
+
+	"github.com/EducationEKT/xserver/x_err"
+	"github.com/EducationEKT/xserver/x_http/x_req"
+	"github.com/EducationEKT/xserver/x_http/x_resp"
+	"github.com/EducationEKT/xserver/x_http/x_router"
+	"github.com/EducationEKT/xserver/x_utils/x_type"
+)
+
+// 一次查询最多扫描的区块数量
+const maxLogBlockRange = 1000
+
+func init() {
+	x_router.Get("/contract/api/logs", contractLogs)
+}
+
+// 按照合约地址、事件名称和区块范围过滤合约事件, address和name可选, toHeight默认为当前高度
+func contractLogs(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	bc := mustGetChain(req)
+	var address []byte
+	if v, exist := req.GetParam("address"); exist {
+		var err error
+		if address, err = hex.DecodeString(x_type.V2String(v)); err != nil {
+			return nil, x_err.New(-1, err.Error())
+		}
+	}
+	name := ""
+	if v, exist := req.GetParam("name"); exist {
+		name = x_type.V2String(v)
+	}
+	from, to := req.MustGetInt64("fromHeight"), bc.GetLastHeight()
+	if v, exist := req.GetParam("toHeight"); exist {
+		if height, ok := x_type.GetInt64(v); ok && height < to {
+			to = height
+		}
+	}
+	if from < 0 || from > to {
+		return nil, x_err.New(-1, fmt.Sprintf("Invalid block range %d to %d.", from, to))
+	}
+	if to-from >= maxLogBlockRange {
+		return nil, x_err.New(-1, fmt.Sprintf("Block range is larger than %d.", maxLogBlockRange))
+	}
+	logs := make([]blockchain.ContractLogEntry, 0)
+	for height := from; height <= to; height++ {
+		header := node.GetBlockByHeight(bc.ChainId, height)
+		if header == nil {
+			return nil, x_err.New(-404, fmt.Sprintf("Header at height %d not found.", height))
+		}
+		entries, err := header.GetLogs(address, name)
+		if err != nil {
+			return nil, x_err.New(-1, err.Error())
+		}
+		logs = append(logs, entries...)
+	}
+	return x_resp.Return(logs, nil)
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/EducationEKT/EKT/blockchain"
+	"github.com/EducationEKT/EKT/conf"
+	"github.com/EducationEKT/EKT/contract"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/gossip"
+	"github.com/EducationEKT/EKT/node"
+
+	"github.com/EducationEKT/xserver/x_err"
+	"github.com/EducationEKT/xserver/x_http/x_req"
+	"github.com/EducationEKT/xserver/x_http/x_resp"
+	"github.com/EducationEKT/xserver/x_http/x_router"
+)
+
+// 提交接收交易之后这么多个区块还没有到账时才重新提交, 避免中继重试时重复提交
+const crossChainResubmitBlocks = 10
+
+// 最多记录的注册请求数量, 超出时丢弃最早的请求
+const maxRegistRequests = 100
+
+// 已经提交了接收交易的跨链转账和提交时的高度
+var receiving sync.Map
+
+// 外部公链提交的注册请求只记录在本地, 委托人核对之后使用ecli crosschain approve签名同意
+var registRequests = struct {
+	sync.Mutex
+	order    []string
+	requests map[string]CrossChainParam
+}{requests: make(map[string]CrossChainParam)}
+
+type CrossChainParam struct {
+	PublicChainId string           `json:"publicChainId"`
+	TokenId       string           `json:"tokenId"`
+	OpCodeId      string           `json:"opCodeId,omitempty"`
+	Delegates     []types.HexBytes `json:"delegates,omitempty"`
+	LocalChainId  string           `json:"localChainId,omitempty"`
+	BlockchainId  int64            `json:"blockchainId,omitempty"`
+}
+
+func init() {
+	x_router.Post("/crosschain/api/handshake", handshake)
+	x_router.Post("/crosschain/api/regist", regist)
+	x_router.Get("/crosschain/api/registration", crossChainRegistration)
+	x_router.Get("/crosschain/api/registRequests", listRegistRequests)
+	x_router.Post("/crosschain/api/receive", receiveCrossChain)
+	x_router.Get("/crosschain/api/externalAddress", externalAddress)
+	x_router.Get("/crosschain/api/parseAddress", parseAddress)
+}
+
+type ExternalAddressInfo struct {
+	types.ExternalAddress
+	Hex  string `json:"hex"`
+	Text string `json:"text"`
+}
+
+func newExternalAddressInfo(external types.ExternalAddress) ExternalAddressInfo {
+	return ExternalAddressInfo{ExternalAddress: external, Hex: hex.EncodeToString(external.Bytes()), Text: external.String()}
+}
+
+// 根据公链id和用户在这条公链上的地址生成68字节的外部地址
+func externalAddress(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	address, err := hex.DecodeString(req.MustGetString("address"))
+	if err != nil {
+		return x_resp.Return(nil, err)
+	}
+	external, err := types.NewExternalAddress(req.MustGetString("publicChainId"), address)
+	if err != nil {
+		return x_resp.Return(nil, err)
+	}
+	return x_resp.Return(newExternalAddressInfo(*external), nil)
+}
+
+// 解析hex或者publicChainId:address格式的外部地址
+func parseAddress(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	text := req.MustGetString("address")
+	external, err := types.ParseExternalAddress(text)
+	if err != nil {
+		data, _ := hex.DecodeString(text)
+		if external, err = types.DecodeExternalAddress(data); err != nil {
+			return x_resp.Return(nil, err)
+		}
+	}
+	return x_resp.Return(newExternalAddressInfo(*external), nil)
+}
+
+// 外部公链和Token是否已经在EKT中注册
+func handshake(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	var param CrossChainParam
+	if err := json.Unmarshal(req.Body, &param); err != nil {
+		return nil, x_err.New(-1, err.Error())
+	}
+	exist := mustGetChain(req).LastHeader().CrossChainActive(param.PublicChainId, param.TokenId)
+	return x_resp.Return(map[string]bool{"exist": exist}, nil)
+}
+
+// 注册外部公链和Token, 只记录注册请求, 所有委托人签名同意之后注册生效
+func regist(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	var param CrossChainParam
+	if err := json.Unmarshal(req.Body, &param); err != nil {
+		return nil, x_err.New(-1, err.Error())
+	}
+	if param.PublicChainId == "" || param.OpCodeId == "" {
+		return nil, x_err.New(-1, "publicChainId and opCodeId are required")
+	}
+	chain := mustGetChain(req)
+	header := chain.LastHeader()
+	if _, exist := header.ResolveTokenId(param.TokenId); !exist {
+		return nil, x_err.New(-404, "token not found")
+	}
+	registration, err := header.GetCrossChainRegistration(param.PublicChainId, param.TokenId)
+	if err == nil && registration.Status == blockchain.CROSSCHAIN_STATUS_ACTIVE {
+		return x_resp.Return(map[string]bool{"exist": true}, nil)
+	}
+	recordRegistRequest(param)
+	return x_resp.Return(map[string]bool{"exist": false}, nil)
+}
+
+func recordRegistRequest(param CrossChainParam) {
+	registRequests.Lock()
+	defer registRequests.Unlock()
+	key := param.PublicChainId + ":" + param.TokenId
+	if _, exist := registRequests.requests[key]; !exist {
+		registRequests.order = append(registRequests.order, key)
+	}
+	registRequests.requests[key] = param
+	if len(registRequests.order) > maxRegistRequests {
+		delete(registRequests.requests, registRequests.order[0])
+		registRequests.order = registRequests.order[1:]
+	}
+}
+
+// 本地记录的尚未生效的注册请求
+func listRegistRequests(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	registRequests.Lock()
+	defer registRequests.Unlock()
+	requests := make([]CrossChainParam, 0, len(registRequests.order))
+	for _, key := range registRequests.order {
+		requests = append(requests, registRequests.requests[key])
+	}
+	return x_resp.Return(requests, nil)
+}
+
+func crossChainRegistration(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	publicChainId := req.MustGetString("publicChainId")
+	tokenId := req.MustGetString("tokenId")
+	return x_resp.Return(mustGetChain(req).LastHeader().GetCrossChainRegistration(publicChainId, tokenId))
+}
+
+// 外部公链的中继提交跨链消息, 已经到账时返回confirmed, 否则验证消息之后由当前节点提交接收交易, 中继需要重试直到confirmed
+func receiveCrossChain(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	var message blockchain.CrossChainMessage
+	if err := json.Unmarshal(req.Body, &message); err != nil {
+		return nil, x_err.New(-1, err.Error())
+	}
+	chain := mustGetChain(req)
+	header := chain.LastHeader()
+	var tx userevent.Transaction
+	if err := json.Unmarshal(message.Tx, &tx); err != nil {
+		return nil, x_err.New(-1, err.Error())
+	}
+	if header.CrossChainReceived(message.SourceChainId, tx.TxId()) {
+		return x_resp.Return(map[string]bool{"confirmed": true}, nil)
+	}
+	if _, err := header.VerifyCrossChainMessage(message); err != nil {
+		return nil, x_err.New(-401, err.Error())
+	}
+	key := message.SourceChainId + ":" + hex.EncodeToString(tx.TxId())
+	if height, exist := receiving.Load(key); exist && header.Height < height.(int64)+crossChainResubmitBlocks {
+		return x_resp.Return(map[string]bool{"confirmed": false}, nil)
+	}
+	receiving.Store(key, header.Height)
+	err := submitCrossChainOp(chain, blockchain.CrossChainOp{
+		Op:            blockchain.CROSSCHAIN_OP_RECEIVE,
+		PublicChainId: message.SourceChainId,
+		Message:       &message,
+	})
+	if err != nil {
+		return nil, x_err.New(-1, err.Error())
+	}
+	return x_resp.Return(map[string]bool{"confirmed": false}, nil)
+}
+
+// 当前节点配置的私钥对应的地址, 没有配置私钥时返回nil
+func nodeAddress() []byte {
+	pub, err := crypto.PubKey(conf.EKTConfig.GetPrivateKey())
+	if err != nil {
+		return nil
+	}
+	return types.FromPubKeyToAddress(pub)
+}
+
+// 使用当前节点的私钥签名并提交跨链系统合约的交易
+func submitCrossChainOp(chain *blockchain.BlockChain, op blockchain.CrossChainOp) error {
+	from := nodeAddress()
+	if from == nil {
+		return errors.New("node has no private key")
+	}
+	account, err := chain.LastHeader().GetAccount(from)
+	if err != nil {
+		return err
+	}
+	nonce := account.GetNonce()
+	if txs := chain.Pool.GetUserTxs(hex.EncodeToString(from)); txs != nil {
+		nonce = txs.Nonce
+	}
+
+	data, _ := json.Marshal(op)
+	to, _ := hex.DecodeString(contract.SYSTEM_AUTHOR + contract.CROSSCHAIN_CONTRACT)
+	tx := userevent.NewTransaction(from, to, time.Now().UnixNano()/1e6, 0, node.SuggestFee(), nonce+1, string(data), types.EKTAddress)
+	if err := userevent.SignTransaction(tx, conf.EKTConfig.GetPrivateKey()); err != nil {
+		return err
+	}
+	if acceptTransaction(chain, tx) {
+		gossip.GetInst().AnnounceTo(chain.ChainId, tx.TxId())
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/hex"
+
+	"github.com/EducationEKT/xserver/x_err"
+	"github.com/EducationEKT/xserver/x_http/x_req"
+	"github.com/EducationEKT/xserver/x_http/x_resp"
+	"github.com/EducationEKT/xserver/x_http/x_router"
+)
+
+func init() {
+	x_router.Get("/governance/api/params", chainParams)
+	x_router.Get("/governance/api/delegates", delegates)
+	x_router.Get("/governance/api/proposals", proposals)
+	x_router.Get("/governance/api/proposal", proposal)
+}
+
+func chainParams(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	return x_resp.Return(mustGetChain(req).ChainParams(), nil)
+}
+
+func delegates(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	return x_resp.Return(mustGetChain(req).LastHeader().Delegates(), nil)
+}
+
+func proposals(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	offset, limit := getPage(req)
+	return x_resp.Return(mustGetChain(req).LastHeader().ListProposals(offset, limit))
+}
+
+func proposal(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	id, err := hex.DecodeString(req.MustGetString("id"))
+	if err != nil {
+		return x_resp.Return(nil, err)
+	}
+	return x_resp.Return(mustGetChain(req).LastHeader().GetProposal(id))
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/hex"
+
+	"github.com/EducationEKT/xserver/x_err"
+	"github.com/EducationEKT/xserver/x_http/x_req"
+	"github.com/EducationEKT/xserver/x_http/x_resp"
+	"github.com/EducationEKT/xserver/x_http/x_router"
+	"github.com/EducationEKT/xserver/x_utils/x_type"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+func init() {
+	x_router.Get("/token/api/list", tokenList)
+	x_router.Get("/token/api/info", tokenInfo)
+	x_router.Get("/token/api/holders", tokenHolders)
+}
+
+func tokenList(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	offset, limit := getPage(req)
+	return x_resp.Return(mustGetChain(req).LastHeader().ListTokens(offset, limit))
+}
+
+// 根据address或者symbol查询Token
+func tokenInfo(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	address, err := getTokenAddress(req)
+	if err != nil {
+		return x_resp.Return(nil, err)
+	}
+	return x_resp.Return(mustGetChain(req).LastHeader().GetTokenInfo(address))
+}
+
+func tokenHolders(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	address, err := getTokenAddress(req)
+	if err != nil {
+		return x_resp.Return(nil, err)
+	}
+	offset, limit := getPage(req)
+	return x_resp.Return(mustGetChain(req).LastHeader().ListTokenHolders(address, offset, limit))
+}
+
+func getTokenAddress(req *x_req.XReq) ([]byte, error) {
+	if symbol, exist := req.GetParam("symbol"); exist {
+		return mustGetChain(req).LastHeader().GetTokenAddress(x_type.V2String(symbol))
+	}
+	return hex.DecodeString(req.MustGetString("address"))
+}
+
+// 分页参数offset和limit, 不传时使用默认值
+func getPage(req *x_req.XReq) (int, int) {
+	offset, limit := 0, DefaultPageLimit
+	if v, exist := req.GetParam("offset"); exist {
+		if value, ok := x_type.GetInt64(v); ok && value > 0 {
+			offset = int(value)
+		}
+	}
+	if v, exist := req.GetParam("limit"); exist {
+		if value, ok := x_type.GetInt64(v); ok && value > 0 && value <= MaxPageLimit {
+			limit = int(value)
+		}
+	}
+	return offset, limit
+}
@@ -3,9 +3,12 @@ package api
 import (
 	"encoding/json"
 
+	"github.com/EducationEKT/EKT/blockchain"
+	"github.com/EducationEKT/EKT/core/types"
 	"github.com/EducationEKT/EKT/core/userevent"
 	"github.com/EducationEKT/EKT/db"
 	"github.com/EducationEKT/EKT/encapdb"
+	"github.com/EducationEKT/EKT/gossip"
 	"github.com/EducationEKT/EKT/log"
 	"github.com/EducationEKT/EKT/node"
 
@@ -17,7 +20,8 @@ import (
 
 func init() {
 	x_router.Get("/transaction/api/fee", fee)
-	x_router.Post("/transaction/api/newTransaction", broadcast, newTransaction)
+	x_router.Post("/transaction/api/newTransaction", newTransaction)
+	x_router.Post("/transaction/api/announce", announceTransactions)
 	x_router.Get("/transaction/api/userTxs", userTxs)
 	x_router.Get("/transaction/api/getReceiptByTxHash", getReceiptByTxHash)
 }
@@ -28,7 +32,7 @@ func fee(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 
 func userTxs(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 	address := req.MustGetString("address")
-	return x_resp.Return(node.GetMainChain().Pool.GetUserTxs(address), nil)
+	return x_resp.Return(mustGetChain(req).Pool.GetUserTxs(address), nil)
 }
 
 func newTransaction(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
@@ -37,16 +41,73 @@ func newTransaction(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 	if err != nil {
 		return nil, x_err.New(-1, err.Error())
 	}
-	if !userevent.ValidateTransaction(tx) {
+	chain := mustGetChain(req)
+	if !userevent.ValidateTransaction(tx, chain.AuthKeys([]userevent.Transaction{tx})) {
 		return nil, x_err.New(-401, "error signature")
 	}
-	if node.GetMainChain().NewTransaction(&tx) {
-		log.LogErr(db.GetDBInst().Set(tx.TxId(), tx.Bytes()))
+	if acceptTransaction(chain, &tx) {
+		gossip.GetInst().AnnounceTo(chain.ChainId, tx.TxId())
 	}
 	return x_resp.Return(tx.TransactionId(), err)
 }
 
+// 其他节点通知的交易hash，交易池中没有的交易从通知的节点拉取，校验通过之后继续通知给其他节点
+func announceTransactions(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
+	var announcement gossip.TxAnnouncement
+	err := json.Unmarshal(req.Body, &announcement)
+	if err != nil {
+		return nil, x_err.New(-1, err.Error())
+	}
+	inst := gossip.GetInst()
+	inst.AddPeer(announcement.Peer)
+
+	chain := node.GetChain(announcement.GetChainId())
+	if chain == nil {
+		return x_resp.Return(0, nil)
+	}
+	hashes := make([][]byte, 0)
+	for _, hash := range announcement.Hashes {
+		if chain.Pool.GetTx(hash) != nil || !inst.MarkSeen(hash) {
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) > 0 {
+		go fetchTransactions(chain, announcement.Peer, hashes)
+	}
+	return x_resp.Return(len(hashes), nil)
+}
+
+func fetchTransactions(chain *blockchain.BlockChain, peer types.Peer, hashes [][]byte) {
+	inst := gossip.GetInst()
+	txs := make([]userevent.Transaction, 0, len(hashes))
+	for _, hash := range hashes {
+		tx := inst.Fetch(peer, hash)
+		if tx == nil {
+			inst.Forget(hash)
+			continue
+		}
+		txs = append(txs, *tx)
+	}
+
+	accepted := make([][]byte, 0)
+	for i, valid := range userevent.DefaultVerifier.VerifyAll(txs, chain.AuthKeys(txs)) {
+		if valid && acceptTransaction(chain, &txs[i]) {
+			accepted = append(accepted, txs[i].TxId())
+		}
+	}
+	inst.AnnounceTo(chain.ChainId, accepted...)
+}
+
+func acceptTransaction(chain *blockchain.BlockChain, tx *userevent.Transaction) bool {
+	if !chain.NewTransaction(tx) {
+		return false
+	}
+	log.LogErr(db.GetDBInst().Set(tx.TxId(), tx.Bytes()))
+	return true
+}
+
 func getReceiptByTxHash(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 	hash := req.MustGetString("hash")
-	return x_resp.Return(encapdb.GetReceiptByTxHash(node.GetMainChain().ChainId, hash), nil)
+	return x_resp.Return(encapdb.GetReceiptByTxHash(mustGetChain(req).ChainId, hash), nil)
 }
@@ -3,8 +3,7 @@ package api
 import (
 	"encoding/hex"
 
-	"github.com/EducationEKT/EKT/conf"
-	"github.com/EducationEKT/EKT/node"
+	"github.com/EducationEKT/EKT/param"
 
 	"github.com/EducationEKT/xserver/x_err"
 	"github.com/EducationEKT/xserver/x_http/x_req"
@@ -20,7 +19,7 @@ func init() {
 }
 
 func genesisAccount(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
-	return x_resp.Return(conf.EKTConfig.GenesisBlockAccounts, nil)
+	return x_resp.Return(param.GenesisAccounts(getChainId(req)), nil)
 }
 
 func userInfo(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
@@ -29,7 +28,7 @@ func userInfo(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 	if err != nil {
 		return x_resp.Return(nil, err)
 	}
-	account, err := node.GetMainChain().LastHeader().GetAccount(hexAddress)
+	account, err := mustGetChain(req).LastHeader().GetAccount(hexAddress)
 	if err != nil {
 		return x_resp.Return(nil, err)
 	}
@@ -39,7 +38,7 @@ func userInfo(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 func userNonce(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 	hexAddress := req.MustGetString("address")
 
-	txs := node.GetMainChain().Pool.GetUserTxs(hexAddress)
+	txs := mustGetChain(req).Pool.GetUserTxs(hexAddress)
 	if txs != nil {
 		return x_resp.Return(txs.Nonce, nil)
 	}
@@ -49,7 +48,7 @@ func userNonce(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 		return x_resp.Return(nil, err)
 	}
 	// get user nonce by user stat tree
-	account, err := node.GetMainChain().LastHeader().GetAccount(address)
+	account, err := mustGetChain(req).LastHeader().GetAccount(address)
 	if err != nil {
 		return x_resp.Return(nil, err)
 	}
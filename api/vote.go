@@ -43,6 +43,6 @@ func voteResult(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 
 func getVotes(req *x_req.XReq) (*x_resp.XRespContainer, *x_err.XErr) {
 	blockHash := req.MustGetString("hash")
-	votes := node.GetVoteResults(1, blockHash)
+	votes := node.GetVoteResults(getChainId(req), blockHash)
 	return x_resp.Return(votes, nil)
 }
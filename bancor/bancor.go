@@ -2,26 +2,40 @@ package bancor
 
 import (
 	"encoding/json"
-	"math"
+	"errors"
+	"math/big"
 
+	"github.com/EducationEKT/EKT/core/types"
 	"github.com/EducationEKT/EKT/core/userevent"
 )
 
+const (
+	// CW以百万分之一为单位
+	MaxWeight = 1000000
+)
+
+var (
+	ErrInvalidAmount  = errors.New("bancor: invalid amount")
+	ErrExceedSupply   = errors.New("bancor: exceed total smart token")
+	ErrExceedReserved = errors.New("bancor: exceed connector reserve")
+)
+
+// Bancor兑换合约的状态, 所有金额都是整数, 计算使用定点数, 结果向下取整
 type Bancor struct {
-	CW float64
+	CW int64
 
-	ConnectAmount       float64
-	InitConnectAmount   float64
+	ConnectAmount       types.Amount
+	InitConnectAmount   types.Amount
 	ConnectTokenAddress string
 
-	TotalSmartToken   float64
-	SelledSmartToken  float64
+	TotalSmartToken   types.Amount
+	SelledSmartToken  types.Amount
 	SmartTokenAddress string
 }
 
-func NewBancor(cw int, connectAmount, smartTokenAmount, totalSmartToken float64, connectTokenAddress, smartTokenAddress string) *Bancor {
+func NewBancor(cw int64, connectAmount, smartTokenAmount, totalSmartToken types.Amount, connectTokenAddress, smartTokenAddress string) *Bancor {
 	return &Bancor{
-		CW: float64(cw),
+		CW: cw,
 
 		ConnectAmount:       connectAmount,
 		InitConnectAmount:   connectAmount,
@@ -49,56 +63,177 @@ func (b Bancor) Data() []byte {
 }
 
 func (b *Bancor) Call(tx userevent.Transaction) (*userevent.TransactionReceipt, []byte) {
-	if tx.TokenAddress == b.SmartTokenAddress {
-		amount := b.Sell(float64(tx.Amount))
-		if b.ConnectAmount < b.InitConnectAmount {
-			return userevent.ContractRefuseTx(tx), nil
-		} else {
-			subTx := userevent.NewSubTransaction(tx.TxId(), tx.To, tx.From, int64(amount), "From bancor contract", b.ConnectTokenAddress)
-			receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
-			subTransactions := make(userevent.SubTransactions, 0)
-			subTransactions = append(subTransactions, *subTx)
-			receipt.SubTransactions = subTransactions
-			return &receipt, b.Data()
-		}
-	} else if tx.TokenAddress == b.ConnectTokenAddress {
-		amount := b.Buy(float64(tx.Amount))
-		if b.TotalSmartToken < b.SelledSmartToken {
-			return userevent.ContractRefuseTx(tx), nil
-		} else {
-			subTx := userevent.NewSubTransaction(tx.TxId(), tx.To, tx.From, int64(amount), "From bancor contract", b.SmartTokenAddress)
-			receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
-			subTransactions := make(userevent.SubTransactions, 0)
-			subTransactions = append(subTransactions, *subTx)
-			receipt.SubTransactions = subTransactions
-			return &receipt, b.Data()
-		}
-	} else {
+	var amount types.Amount
+	var tokenAddress string
+	var err error
+	switch tx.TokenAddress {
+	case b.SmartTokenAddress:
+		amount, err = b.Sell(tx.Amount)
+		tokenAddress = b.ConnectTokenAddress
+	case b.ConnectTokenAddress:
+		amount, err = b.Buy(tx.Amount)
+		tokenAddress = b.SmartTokenAddress
+	default:
+		err = ErrInvalidAmount
+	}
+	if err != nil || amount.Sign() <= 0 {
 		return userevent.ContractRefuseTx(tx), nil
 	}
+	subTx := userevent.NewSubTransaction(tx.TxId(), tx.To, tx.From, amount, "From bancor contract", tokenAddress)
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	subTransactions := make(userevent.SubTransactions, 0)
+	subTransactions = append(subTransactions, *subTx)
+	receipt.SubTransactions = subTransactions
+	return &receipt, b.Data()
+}
+
+// 使用ca个连接器Token可以买到的智能Token数量, 不修改状态
+func (b Bancor) QuoteBuy(ca types.Amount) (types.Amount, error) {
+	amount, err := PurchaseReturn(b.SelledSmartToken, b.ConnectAmount, b.CW, ca)
+	if err != nil {
+		return types.Amount{}, err
+	}
+	if b.SelledSmartToken.Add(amount).Cmp(b.TotalSmartToken) > 0 {
+		return types.Amount{}, ErrExceedSupply
+	}
+	return amount, nil
+}
+
+// 卖出s个智能Token可以得到的连接器Token数量, 不修改状态
+func (b Bancor) QuoteSell(s types.Amount) (types.Amount, error) {
+	amount, err := SaleReturn(b.SelledSmartToken, b.ConnectAmount, b.CW, s)
+	if err != nil {
+		return types.Amount{}, err
+	}
+	// 初始的连接器Token不能被取出
+	if b.ConnectAmount.Sub(amount).Cmp(b.InitConnectAmount) < 0 {
+		return types.Amount{}, ErrExceedReserved
+	}
+	return amount, nil
 }
 
-func (b *Bancor) Buy(ca float64) float64 {
-	amount := float64(int64(b.SelledSmartToken * (math.Pow(1+(ca/b.ConnectAmount), b.CW/1000000) - 1)))
-	b.ConnectAmount += ca
-	b.SelledSmartToken += amount
-	return amount
+// 向连接器支付amount可以买到的智能Token数量
+// supply * ((1 + amount/balance)^weight - 1)
+func PurchaseReturn(supply, balance types.Amount, weight int64, amount types.Amount) (types.Amount, error) {
+	if amount.Sign() <= 0 || balance.Sign() <= 0 || supply.Sign() <= 0 || weight <= 0 || weight > MaxWeight {
+		return types.Amount{}, ErrInvalidAmount
+	}
+	pow, err := fixedPow(balance.Add(amount).Big(), balance.Big(), big.NewInt(weight), big.NewInt(MaxWeight))
+	if err != nil {
+		return types.Amount{}, err
+	}
+	result := pow.Sub(pow, fixedOne)
+	result.Mul(result, supply.Big())
+	result.Quo(result, fixedOne)
+	return types.NewAmountFromBig(result), nil
 }
 
-func (b *Bancor) Sell(amount float64) float64 {
-	accuracy := int(1e5)
+// 卖出amount个智能Token可以从连接器得到的数量
+// balance * (1 - (1 - amount/supply)^(1/weight))
+func SaleReturn(supply, balance types.Amount, weight int64, amount types.Amount) (types.Amount, error) {
+	if amount.Sign() <= 0 || amount.Cmp(supply) > 0 || weight <= 0 || weight > MaxWeight {
+		return types.Amount{}, ErrInvalidAmount
+	}
+	if amount.Cmp(supply) == 0 {
+		return balance, nil
+	}
+	pow, err := fixedPow(supply.Sub(amount).Big(), supply.Big(), big.NewInt(MaxWeight), big.NewInt(weight))
+	if err != nil {
+		return types.Amount{}, err
+	}
+	result := new(big.Int).Sub(fixedOne, pow)
+	result.Mul(result, balance.Big())
+	result.Quo(result, fixedOne)
+	return types.NewAmountFromBig(result), nil
+}
 
-	total := float64(0)
-	for i := 0; i < accuracy; i++ {
-		amt := amount / float64(accuracy)
-		total += b.sell(amt)
+func (b *Bancor) Buy(ca types.Amount) (types.Amount, error) {
+	amount, err := b.QuoteBuy(ca)
+	if err != nil {
+		return types.Amount{}, err
+	}
+	b.ConnectAmount = b.ConnectAmount.Add(ca)
+	b.SelledSmartToken = b.SelledSmartToken.Add(amount)
+	return amount, nil
+}
+
+func (b *Bancor) Sell(s types.Amount) (types.Amount, error) {
+	amount, err := b.QuoteSell(s)
+	if err != nil {
+		return types.Amount{}, err
+	}
+	b.SelledSmartToken = b.SelledSmartToken.Sub(s)
+	b.ConnectAmount = b.ConnectAmount.Sub(amount)
+	return amount, nil
+}
+
+// 当前的价格, 每个智能Token对应的连接器Token数量: C / (S * CW)
+func (b Bancor) SpotPrice() *big.Rat {
+	if b.SelledSmartToken.Sign() <= 0 || b.CW <= 0 {
+		return new(big.Rat)
+	}
+	denominator := new(big.Int).Mul(b.SelledSmartToken.Big(), big.NewInt(b.CW))
+	numerator := new(big.Int).Mul(b.ConnectAmount.Big(), big.NewInt(MaxWeight))
+	return new(big.Rat).SetFrac(numerator, denominator)
+}
+
+const (
+	SIDE_BUY  = "buy"
+	SIDE_SELL = "sell"
+)
+
+// 兑换报价, 价格均为每个智能Token对应的连接器Token数量
+type Quote struct {
+	Side      string       `json:"side"`
+	Amount    types.Amount `json:"amount"`
+	Return    types.Amount `json:"return"`
+	SpotPrice string       `json:"spotPrice"`
+	Price     string       `json:"price"`
+	Slippage  string       `json:"slippage"` // 成交价格相对于当前价格的偏离, 百分比
+}
+
+func (b Bancor) Quote(side string, amount types.Amount) (*Quote, error) {
+	var result types.Amount
+	var err error
+	switch side {
+	case SIDE_BUY:
+		result, err = b.QuoteBuy(amount)
+	case SIDE_SELL:
+		result, err = b.QuoteSell(amount)
+	default:
+		err = ErrInvalidAmount
+	}
+	if err != nil {
+		return nil, err
+	}
+	spot := b.SpotPrice()
+	quote := &Quote{Side: side, Amount: amount, Return: result, SpotPrice: FormatPrice(spot)}
+	if result.Sign() == 0 {
+		return quote, nil
+	}
+	price := ConversionPrice(side, amount, result)
+	quote.Price = FormatPrice(price)
+	if spot.Sign() > 0 {
+		slippage := new(big.Rat).Sub(price, spot)
+		slippage.Quo(slippage, spot)
+		slippage.Mul(slippage, big.NewRat(100, 1))
+		quote.Slippage = slippage.FloatString(4)
+	}
+	return quote, nil
+}
+
+// 一次兑换的成交价格
+func ConversionPrice(side string, amount, result types.Amount) *big.Rat {
+	connect, smart := amount, result
+	if side == SIDE_SELL {
+		connect, smart = result, amount
+	}
+	if smart.Sign() <= 0 {
+		return new(big.Rat)
 	}
-	return float64(int64(total))
+	return new(big.Rat).SetFrac(connect.Big(), smart.Big())
 }
 
-func (b *Bancor) sell(amount float64) float64 {
-	cnt := b.ConnectAmount * (math.Pow((b.SelledSmartToken+amount)/b.SelledSmartToken, 1000000/b.CW) - 1)
-	b.SelledSmartToken -= amount
-	b.ConnectAmount -= cnt
-	return cnt
+func FormatPrice(price *big.Rat) string {
+	return price.FloatString(18)
 }
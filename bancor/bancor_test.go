@@ -1,42 +1,122 @@
 package bancor
 
 import (
-	"fmt"
+	"math/big"
 	"testing"
+
+	"github.com/EducationEKT/EKT/core/types"
 )
 
+func amount(v int64) types.Amount {
+	return types.NewAmount(v)
+}
+
+// CW为50%时买入得到 S*((1+ca/C)^0.5-1), 结果向下取整; 拆成多次小额买入不会得到更多的Token
 func TestBancor_Buy(t *testing.T) {
-	bancor1 := NewBancor(500000, 1, 1, 1e8, "", "1")
-	bancor2 := NewBancor(500000, 1, 1, 1e8, "", "1")
-	amount := bancor1.Buy(1000000)
-	fmt.Println(amount)
-	var cnt float64 = 0
+	bancor1 := NewBancor(500000, amount(1), amount(1), amount(1e8), "", "1")
+	bancor2 := NewBancor(500000, amount(1), amount(1), amount(1e8), "", "1")
+	amount1, err := bancor1.Buy(amount(1000000))
+	if err != nil || amount1.Cmp(amount(999)) != 0 {
+		t.Fatal("buy", amount1, err)
+	}
+	cnt := types.NewAmount(0)
 	for i := 0; i < 10000; i++ {
-		cnt += bancor2.Buy(100)
+		a, _ := bancor2.Buy(amount(100))
+		cnt = cnt.Add(a)
+	}
+	if cnt.Sign() <= 0 || cnt.Cmp(amount1) > 0 {
+		t.Fatal("split buy", cnt, amount1)
 	}
-	fmt.Println(cnt)
 }
 
+// 连接Token和智能Token的比例相同时, 买入数量只和ca/C有关
 func TestBancor_Buy2(t *testing.T) {
-	bancor1 := NewBancor(500000, 1, 1, 1e8, "", "1")
-	amount1 := bancor1.Buy(1e5)
-	fmt.Println(amount1)
-
-	bancor2 := NewBancor(500000, 1e8, 1, 1e8, "", "1")
-	amount2 := bancor2.Buy(1e5 * 1e8)
-	fmt.Println(amount2)
+	bancor1 := NewBancor(500000, amount(1), amount(1), amount(1e8), "", "1")
+	amount1, _ := bancor1.Buy(amount(1e5))
+	bancor2 := NewBancor(500000, amount(1e8), amount(1), amount(1e8), "", "1")
+	amount2, _ := bancor2.Buy(amount(1e5 * 1e8))
+	if amount1.Cmp(amount(315)) != 0 || amount2.Cmp(amount1) != 0 {
+		t.Fatal("buy", amount1, amount2)
+	}
 }
 
+// 买入之后全部卖出, 取整之后得到的连接Token不会超过买入时支付的数量
 func TestBancor_Sell(t *testing.T) {
-	bancor := NewBancor(500000, 1, 1, 1e8, "", "1")
-	amount := bancor.Buy(100000)
-	fmt.Println(amount)
-	fmt.Println(bancor.Sell(amount))
+	bancor := NewBancor(500000, amount(1), amount(1), amount(1e8), "", "1")
+	amount1, _ := bancor.Buy(amount(100000))
+	sold, err := bancor.Sell(amount1)
+	if err != nil || sold.Cmp(amount(100000)) > 0 || sold.Cmp(amount(99999)) < 0 {
+		t.Fatal("sell", amount1, sold, err)
+	}
 }
 
 func TestBancor_Sell2(t *testing.T) {
-	bancor := NewBancor(500000, 250, 1000, 1e8, "", "1")
-	amount := bancor.Buy(10)
-	fmt.Println(amount)
-	fmt.Println(bancor.Sell(amount))
+	bancor := NewBancor(500000, amount(250), amount(1000), amount(1e8), "", "1")
+	amount1, _ := bancor.Buy(amount(10))
+	if amount1.Cmp(amount(19)) != 0 {
+		t.Fatal("buy", amount1)
+	}
+	sold, err := bancor.Sell(amount1)
+	if err != nil || sold.Sign() <= 0 || sold.Cmp(amount(10)) > 0 {
+		t.Fatal("sell", sold, err)
+	}
+}
+
+// CW为50%时 S*((1+ca/C)^0.5-1) 和 C*(1-(1-s/S)^2) 都有精确的整数结果
+func TestBancor_Exact(t *testing.T) {
+	bancor := NewBancor(500000, amount(1e8), amount(1e6), amount(1e12), "", "1")
+	before := bancor.Data()
+	quote, err := bancor.QuoteBuy(amount(3e8))
+	if err != nil || quote.Cmp(amount(1e6)) != 0 {
+		t.Fatal("quote buy", quote, err)
+	}
+	if string(bancor.Data()) != string(before) {
+		t.Fatal("quote modified state")
+	}
+
+	bought, _ := bancor.Buy(amount(3e8))
+	if bought.Cmp(quote) != 0 {
+		t.Fatal("buy differs from quote")
+	}
+	sold, err := bancor.Sell(amount(1e6))
+	if err != nil || sold.Cmp(amount(3e8)) > 0 || sold.Cmp(amount(3e8-1)) < 0 {
+		t.Fatal("sell", sold, err)
+	}
+}
+
+func TestFixedPow(t *testing.T) {
+	// 2^10 = 1024
+	pow, err := fixedPow(big.NewInt(2), big.NewInt(1), big.NewInt(10), big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := new(big.Int).Sub(pow, new(big.Int).Mul(big.NewInt(1024), fixedOne))
+	if diff.Abs(diff).Cmp(big.NewInt(1e6)) > 0 {
+		t.Fatal("2^10 =", pow)
+	}
+}
+
+func TestConverter_Convert(t *testing.T) {
+	connectors := []Connector{{TokenAddress: "a", Weight: 250000}, {TokenAddress: "b", Weight: 250000}}
+	converter, err := NewConverter("s", amount(1e6), connectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converter.Fund("a", amount(1e8))
+	converter.Fund("b", amount(1e8))
+
+	quote, err := converter.QuoteConvert("a", "b", amount(1e6))
+	if err != nil || quote.Sign() <= 0 || quote.Cmp(amount(1e6)) >= 0 {
+		t.Fatal("quote", quote, err)
+	}
+	result, err := converter.Convert("a", "b", amount(1e6))
+	if err != nil || result.Cmp(quote) != 0 {
+		t.Fatal("convert differs from quote", result, err)
+	}
+	if converter.Supply.Cmp(amount(1e6)) != 0 {
+		t.Fatal("supply changed by cross connector conversion", converter.Supply)
+	}
+	if _, err = NewConverter("s", amount(1e6), append(connectors, Connector{TokenAddress: "c", Weight: 600000})); err == nil {
+		t.Fatal("total weight exceeds 100%")
+	}
 }
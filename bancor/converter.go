@@ -0,0 +1,158 @@
+package bancor
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/EducationEKT/EKT/core/types"
+)
+
+const (
+	MaxConnectors = 8
+)
+
+var (
+	ErrInvalidConverter = errors.New("bancor: invalid converter")
+	ErrInvalidToken     = errors.New("bancor: token is not supported by converter")
+)
+
+type Connector struct {
+	TokenAddress string       `json:"tokenAddress"`
+	Weight       int64        `json:"weight"` // 以百万分之一为单位
+	Balance      types.Amount `json:"balance"`
+}
+
+// 支持多个连接器的Bancor兑换合约, 每个连接器有独立的权重, 所有连接器的权重之和不超过100%
+// 智能Token和连接器Token都是链上已经发行的Token, 兑换合约账户中持有的智能Token用于卖给购买者
+type Converter struct {
+	SmartTokenAddress string       `json:"smartTokenAddress"`
+	Supply            types.Amount `json:"supply"`
+	Connectors        []Connector  `json:"connectors"`
+}
+
+func NewConverter(smartTokenAddress string, supply types.Amount, connectors []Connector) (*Converter, error) {
+	converter := &Converter{
+		SmartTokenAddress: smartTokenAddress,
+		Supply:            supply,
+		Connectors:        make([]Connector, 0, len(connectors)),
+	}
+	if supply.Sign() <= 0 || len(connectors) == 0 || len(connectors) > MaxConnectors {
+		return nil, ErrInvalidConverter
+	}
+	totalWeight := int64(0)
+	for _, connector := range connectors {
+		if connector.Weight <= 0 || connector.TokenAddress == smartTokenAddress {
+			return nil, ErrInvalidConverter
+		}
+		if _, exist := converter.connector(connector.TokenAddress); exist {
+			return nil, ErrInvalidConverter
+		}
+		totalWeight += connector.Weight
+		if totalWeight > MaxWeight {
+			return nil, ErrInvalidConverter
+		}
+		converter.Connectors = append(converter.Connectors, Connector{TokenAddress: connector.TokenAddress, Weight: connector.Weight})
+	}
+	return converter, nil
+}
+
+func (converter *Converter) Recover(data []byte) bool {
+	var c Converter
+	err := json.Unmarshal(data, &c)
+	if err == nil {
+		*converter = c
+		return true
+	}
+	return false
+}
+
+func (converter Converter) Data() []byte {
+	data, _ := json.Marshal(converter)
+	return data
+}
+
+func (converter Converter) connector(tokenAddress string) (int, bool) {
+	for i, connector := range converter.Connectors {
+		if connector.TokenAddress == tokenAddress {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (converter Converter) Supports(tokenAddress string) bool {
+	_, exist := converter.connector(tokenAddress)
+	return exist || tokenAddress == converter.SmartTokenAddress
+}
+
+// 向连接器中存入Token, 增加连接器的余额
+func (converter *Converter) Fund(tokenAddress string, amount types.Amount) error {
+	i, exist := converter.connector(tokenAddress)
+	if !exist || amount.Sign() <= 0 {
+		return ErrInvalidToken
+	}
+	converter.Connectors[i].Balance = converter.Connectors[i].Balance.Add(amount)
+	return nil
+}
+
+// 把amount个from兑换为to可以得到的数量, 不修改状态
+func (converter Converter) QuoteConvert(from, to string, amount types.Amount) (types.Amount, error) {
+	return converter.clone().Convert(from, to, amount)
+}
+
+// 把amount个from兑换为to
+// 连接器Token兑换智能Token为买入, 智能Token兑换连接器Token为卖出, 两个连接器之间先买入再卖出
+func (converter *Converter) Convert(from, to string, amount types.Amount) (types.Amount, error) {
+	if from == to {
+		return types.Amount{}, ErrInvalidToken
+	}
+	if from != converter.SmartTokenAddress {
+		i, exist := converter.connector(from)
+		if !exist {
+			return types.Amount{}, ErrInvalidToken
+		}
+		connector := &converter.Connectors[i]
+		bought, err := PurchaseReturn(converter.Supply, connector.Balance, connector.Weight, amount)
+		if err != nil {
+			return types.Amount{}, err
+		}
+		connector.Balance = connector.Balance.Add(amount)
+		converter.Supply = converter.Supply.Add(bought)
+		if to == converter.SmartTokenAddress {
+			return bought, nil
+		}
+		from, amount = converter.SmartTokenAddress, bought
+	}
+
+	i, exist := converter.connector(to)
+	if !exist {
+		return types.Amount{}, ErrInvalidToken
+	}
+	connector := &converter.Connectors[i]
+	result, err := SaleReturn(converter.Supply, connector.Balance, connector.Weight, amount)
+	if err != nil {
+		return types.Amount{}, err
+	}
+	connector.Balance = connector.Balance.Sub(result)
+	converter.Supply = converter.Supply.Sub(amount)
+	return result, nil
+}
+
+// 以连接器Token计价的智能Token价格: balance / (supply * weight)
+func (converter Converter) SpotPrice(tokenAddress string) *big.Rat {
+	i, exist := converter.connector(tokenAddress)
+	if !exist || converter.Supply.Sign() <= 0 {
+		return new(big.Rat)
+	}
+	connector := converter.Connectors[i]
+	denominator := new(big.Int).Mul(converter.Supply.Big(), big.NewInt(connector.Weight))
+	numerator := new(big.Int).Mul(connector.Balance.Big(), big.NewInt(MaxWeight))
+	return new(big.Rat).SetFrac(numerator, denominator)
+}
+
+func (converter Converter) clone() *Converter {
+	c := converter
+	c.Connectors = append([]Connector{}, converter.Connectors...)
+	return &c
+}
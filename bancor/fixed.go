@@ -0,0 +1,102 @@
+package bancor
+
+import (
+	"errors"
+	"math/big"
+)
+
+// 定点数运算, 定点数v表示的实数为v/fixedOne, 所有运算结果向零截断, 保证在不同平台上的结果完全一致
+const (
+	fixedDigits = 40
+	// exp的结果最多为2^maxExpShift, 超出时认为溢出
+	maxExpShift = 1024
+)
+
+var (
+	fixedOne = new(big.Int).Exp(big.NewInt(10), big.NewInt(fixedDigits), nil)
+	fixedTwo = new(big.Int).Lsh(fixedOne, 1)
+	fixedLn2 = fixedAtanh2(fixedFrac(big.NewInt(1), big.NewInt(3)))
+
+	ErrOutOfRange = errors.New("bancor: value out of range")
+)
+
+func fixedMul(a, b *big.Int) *big.Int {
+	result := new(big.Int).Mul(a, b)
+	return result.Quo(result, fixedOne)
+}
+
+func fixedDiv(a, b *big.Int) *big.Int {
+	result := new(big.Int).Mul(a, fixedOne)
+	return result.Quo(result, b)
+}
+
+// 把分数num/den转换为定点数
+func fixedFrac(num, den *big.Int) *big.Int {
+	return fixedDiv(num, den)
+}
+
+// 2*atanh(z) = ln((1+z)/(1-z)), |z| <= 1/3时收敛很快
+func fixedAtanh2(z *big.Int) *big.Int {
+	z2 := fixedMul(z, z)
+	sum, term := new(big.Int), new(big.Int).Set(z)
+	for n := int64(1); term.Sign() != 0; n += 2 {
+		sum.Add(sum, new(big.Int).Quo(term, big.NewInt(n)))
+		term = fixedMul(term, z2)
+	}
+	return sum.Lsh(sum, 1)
+}
+
+// 自然对数, x必须大于0
+// x = m * 2^k, m在[1, 2)之间, ln(x) = k*ln(2) + ln(m)
+func fixedLn(x *big.Int) *big.Int {
+	m, k := new(big.Int).Set(x), int64(0)
+	for m.Cmp(fixedTwo) >= 0 {
+		m.Rsh(m, 1)
+		k++
+	}
+	for m.Cmp(fixedOne) < 0 {
+		m.Lsh(m, 1)
+		k--
+	}
+	z := fixedDiv(new(big.Int).Sub(m, fixedOne), new(big.Int).Add(m, fixedOne))
+	result := fixedAtanh2(z)
+	return result.Add(result, new(big.Int).Mul(big.NewInt(k), fixedLn2))
+}
+
+// 自然指数
+// x = k*ln(2) + r, r在[0, ln(2))之间, exp(x) = 2^k * exp(r)
+func fixedExp(x *big.Int) (*big.Int, error) {
+	k, r := new(big.Int).DivMod(x, fixedLn2, new(big.Int))
+	if !k.IsInt64() || k.Int64() > maxExpShift {
+		return nil, ErrOutOfRange
+	}
+	sum, term := new(big.Int).Set(fixedOne), new(big.Int).Set(fixedOne)
+	for n := int64(1); term.Sign() != 0; n++ {
+		term = fixedMul(term, r)
+		term.Quo(term, big.NewInt(n))
+		sum.Add(sum, term)
+	}
+	if shift := k.Int64(); shift >= 0 {
+		sum.Lsh(sum, uint(shift))
+	} else if shift > -maxExpShift {
+		sum.Rsh(sum, uint(-shift))
+	} else {
+		sum.SetInt64(0)
+	}
+	return sum, nil
+}
+
+// (baseNum/baseDen)^(expNum/expDen), 底数必须大于0
+func fixedPow(baseNum, baseDen, expNum, expDen *big.Int) (*big.Int, error) {
+	if baseNum.Sign() <= 0 || baseDen.Sign() <= 0 || expDen.Sign() <= 0 {
+		return nil, ErrOutOfRange
+	}
+	base := fixedFrac(baseNum, baseDen)
+	if base.Sign() <= 0 {
+		return nil, ErrOutOfRange
+	}
+	ln := fixedLn(base)
+	ln.Mul(ln, expNum)
+	ln.Quo(ln, expDen)
+	return fixedExp(ln)
+}
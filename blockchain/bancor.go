@@ -0,0 +1,158 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/EducationEKT/EKT/bancor"
+	"github.com/EducationEKT/EKT/contract"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+)
+
+// EKT和Gas之间的Bancor兑换合约, 连接器为EKT, 智能Token为Gas
+func NewEKTGasBancor() *bancor.Bancor {
+	return bancor.NewBancor(contract.EKT_GAS_PARAM_CW, types.NewAmount(contract.EKT_GAS_PARAM_INIT_CONNECT_TOKEN), types.NewAmount(contract.EKT_GAS_PARAM_INIT_SMART_TOKEN),
+		types.NewAmount(contract.EKT_GAS_PARAM_TOTAL_SMART_TOKEN), types.EKTAddress, types.GasAddress)
+}
+
+// 系统合约的账户地址
+func SystemAccountAddress() []byte {
+	address, _ := hex.DecodeString(contract.SYSTEM_AUTHOR)
+	return address
+}
+
+// 系统合约的完整地址, 前32字节为SYSTEM_AUTHOR
+func SystemContractAddress(contractId string) []byte {
+	address, _ := hex.DecodeString(contract.SYSTEM_AUTHOR + contractId)
+	return address
+}
+
+// 在系统账户下安装Bancor合约, 合约账户持有尚未卖出的Gas, 合约状态保存在ContractData.Contract中
+func (header *Header) InstallBancor(account *types.Account) types.ContractAccount {
+	b := NewEKTGasBancor()
+	address, _ := hex.DecodeString(contract.EKT_GAS_BANCOR_CONTRACT)
+	contractData := types.ContractData{
+		Prop: types.ContractProp{
+			Name:   "EKT-Gas Bancor",
+			Author: contract.SYSTEM_AUTHOR,
+		},
+		Contract: string(b.Data()),
+	}
+	contractAccount := types.NewContractAccount(address, nil, contractData)
+	contractAccount.Gas = b.TotalSmartToken.Sub(b.SelledSmartToken)
+	if account.Contracts == nil {
+		account.Contracts = make(map[string]types.ContractAccount)
+	}
+	account.Contracts[contract.EKT_GAS_BANCOR_CONTRACT] = *contractAccount
+	return *contractAccount
+}
+
+// 在FORK_BANCOR激活的区块安装Bancor合约, 合约账户持有的Gas在这时发行
+func (header *Header) installSystemBancor() {
+	account, err := header.GetAccount(SystemAccountAddress())
+	if err != nil || account == nil {
+		account = types.NewAccount(SystemAccountAddress())
+	}
+	if _, exist := account.Contracts[contract.EKT_GAS_BANCOR_CONTRACT]; exist {
+		return
+	}
+	header.InstallBancor(account)
+	logErr(header.StatTree.MustInsert(account.Address, account.ToBytes()))
+}
+
+// 读取Bancor合约账户, 没有安装时返回false
+func (header *Header) getBancorAccount() (*types.Account, types.ContractAccount, bool) {
+	account, err := header.GetAccount(SystemAccountAddress())
+	if err != nil || account == nil {
+		return nil, types.ContractAccount{}, false
+	}
+	contractAccount, exist := account.Contracts[contract.EKT_GAS_BANCOR_CONTRACT]
+	return account, contractAccount, exist
+}
+
+// 使用EKT购买Gas或者卖出Gas换回EKT
+func (block *Block) BancorCall(tx userevent.Transaction) *userevent.TransactionReceipt {
+	if tx.Amount.Sign() <= 0 {
+		return systemRefused(tx)
+	}
+	header := block.GetHeader()
+	_, contractAccount, exist := header.getBancorAccount()
+	b := NewEKTGasBancor()
+	if !exist || !b.Recover([]byte(contractAccount.ContractData.Contract)) {
+		return systemRefused(tx)
+	}
+	receipt, data := b.Call(tx)
+	if receipt == nil || !receipt.Success {
+		return systemRefused(tx)
+	}
+
+	subTx := userevent.NewSubTransaction(tx.TxId(), tx.From, tx.To, tx.Amount, tx.Data, tx.TokenAddress)
+	txs := append(receipt.SubTransactions, *subTx)
+	if !header.NewSubTransaction(txs) {
+		_receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_CHECK_CONTRACT_SUBTX_ERROR)
+		return &_receipt
+	}
+
+	// 转账之后重新读取系统账户, 保存合约状态
+	account, contractAccount, _ := header.getBancorAccount()
+	contractAccount.ContractData.Contract = string(data)
+	account.Contracts[contract.EKT_GAS_BANCOR_CONTRACT] = contractAccount
+	logErr(header.StatTree.MustInsert(account.Address, account.ToBytes()))
+
+	receipt.SubTransactions = txs
+	return receipt
+}
+
+// 读取当前的Bancor合约状态, 尚未安装时返回初始状态
+func (header Header) GetBancor() *bancor.Bancor {
+	b := NewEKTGasBancor()
+	account, err := header.GetAccount(SystemAccountAddress())
+	if err != nil || account == nil {
+		return b
+	}
+	if contractAccount, exist := account.Contracts[contract.EKT_GAS_BANCOR_CONTRACT]; exist {
+		b.Recover([]byte(contractAccount.ContractData.Contract))
+	}
+	return b
+}
+
+type BancorPricePoint struct {
+	Height    int64          `json:"height"`
+	Timestamp int64          `json:"timestamp"`
+	TxId      types.HexBytes `json:"txId"`
+	Side      string         `json:"side"`
+	Amount    types.Amount   `json:"amount"`
+	Return    types.Amount   `json:"return"`
+	Price     string         `json:"price"`
+}
+
+// 从本地保存的区块回执中提取Bancor的成交记录
+func (header Header) BancorPricePoints() ([]BancorPricePoint, error) {
+	points := make([]BancorPricePoint, 0)
+	address := SystemContractAddress(contract.EKT_GAS_BANCOR_CONTRACT)
+	err := header.IterateReceipts(func(receipt userevent.TransactionReceipt) bool {
+		if !receipt.Success || len(receipt.SubTransactions) != 2 {
+			return true
+		}
+		out, in := receipt.SubTransactions[0], receipt.SubTransactions[1]
+		if !bytes.Equal(out.From, address) || !bytes.Equal(in.To, address) {
+			return true
+		}
+		side := bancor.SIDE_BUY
+		if in.TokenAddress == types.GasAddress {
+			side = bancor.SIDE_SELL
+		}
+		points = append(points, BancorPricePoint{
+			Height:    header.Height,
+			Timestamp: header.Timestamp,
+			TxId:      receipt.TxId,
+			Side:      side,
+			Amount:    in.Amount,
+			Return:    out.Amount,
+			Price:     bancor.FormatPrice(bancor.ConversionPrice(side, in.Amount, out.Amount)),
+		})
+		return true
+	})
+	return points, err
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"github.com/EducationEKT/EKT/downloader"
+	"sort"
 	"strconv"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/EducationEKT/EKT/crypto"
 	"github.com/EducationEKT/EKT/db"
 	"github.com/EducationEKT/EKT/log"
+	"github.com/EducationEKT/EKT/param"
 	"github.com/EducationEKT/EKT/vm"
 )
 
@@ -34,6 +36,15 @@ type Block struct {
 	Miner               types.Peer             `json:"miner"`
 	Transactions        userevent.Transactions `json:"-"`
 	TransactionReceipts userevent.Receipts     `json:"-"`
+
+	vmTimeout time.Duration // 合约执行的本地超时, 为0时使用共识参数中的VMCallTimeout
+}
+
+func (block *Block) vmCallTimeout() time.Duration {
+	if block.vmTimeout > 0 {
+		return block.vmTimeout
+	}
+	return block.GetHeader().ChainParams().VMTimeout()
 }
 
 func GetBlockFromBytes(data []byte) *Block {
@@ -114,6 +125,8 @@ func (block *Block) NewTransaction(tx userevent.Transaction) *userevent.Transact
 		return block.NormalTransfer(tx)
 	case types.ContractAddressLength:
 		return block.ContractCall(tx)
+	case types.ExternalAddressLength:
+		return block.ExternalTransfer(tx)
 	default:
 		receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_INVALID_ADDRESS)
 		return &receipt
@@ -121,6 +134,9 @@ func (block *Block) NewTransaction(tx userevent.Transaction) *userevent.Transact
 }
 
 func (block *Block) ContractCall(tx userevent.Transaction) *userevent.TransactionReceipt {
+	if IsSystemContract(tx.To) {
+		return block.SystemCall(tx)
+	}
 	toAccountAddress, toContractAddress := tx.To[:32], tx.To[32:]
 	to, err := block.GetHeader().GetAccount(toAccountAddress)
 	if err != nil || to == nil {
@@ -130,19 +146,34 @@ func (block *Block) ContractCall(tx userevent.Transaction) *userevent.Transactio
 		receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_INVALID_ADDRESS)
 		return &receipt
 	}
-	_vm := vm.NewVM(block.GetHeader(), db.GetDBInst())
 	contractAccount := to.Contracts[hex.EncodeToString(toContractAddress)]
-	txs, data, err := _vm.ContractCall(tx, VM_CALL_TIMEOUT)
+	if contractAccount.Native == types.NativeBancor {
+		return block.ConverterCall(tx)
+	}
+	_vm := block.newVM(tx)
+	return block.chargeGas(tx, _vm, block.contractCall(tx, _vm, to, contractAccount))
+}
+
+func (block *Block) contractCall(tx userevent.Transaction, _vm *vm.Otto, to *types.Account, contractAccount types.ContractAccount) *userevent.TransactionReceipt {
+	toAccountAddress, toContractAddress := tx.To[:32], tx.To[32:]
+	txs, data, err := _vm.ContractCall(tx, block.vmCallTimeout())
 	if err != nil {
-		if err == vm.TIMEOUT_ERROR {
-			receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_CONTRACT_TIMEOUT)
-			return &receipt
+		if err == vm.TIMEOUT_ERROR || err == vm.OUT_OF_GAS_ERROR {
+			return vmFailReceipt(tx, err, userevent.FailType_CONTRACT_ERROR)
 		}
 		return userevent.ContractRefuseTx(tx)
 	}
-	contractAccount.ContractData.Contract = string(data)
-	to.Contracts[hex.EncodeToString(toContractAddress)] = contractAccount
-	block.GetHeader().StatTree.MustInsert(toAccountAddress, to.ToBytes())
+	roots, err := _vm.CommitStorage()
+	if err != nil {
+		return userevent.ContractRefuseTx(tx)
+	}
+	// 升级之前合约数据在检查子交易之前写入, 重放历史区块时保持不变
+	storageFork := param.IsActive(param.FORK_CONTRACT_STORAGE, block.GetHeader().Height)
+	if !storageFork {
+		contractAccount.ContractData.Contract = string(data)
+		to.Contracts[hex.EncodeToString(toContractAddress)] = contractAccount
+		block.GetHeader().StatTree.MustInsert(toAccountAddress, to.ToBytes())
+	}
 
 	if !block.CheckSubTransaction(tx, txs) {
 		_receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_CHECK_CONTRACT_SUBTX_ERROR)
@@ -153,6 +184,14 @@ func (block *Block) ContractCall(tx userevent.Transaction) *userevent.Transactio
 	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
 	receipt.Success = block.GetHeader().NewSubTransaction(txs)
 	receipt.SubTransactions = txs
+	// 交易失败时合约记录的事件和对数据、存储的修改一起丢弃
+	if receipt.Success {
+		receipt.Logs = _vm.Logs()
+		if storageFork {
+			logErr(block.GetHeader().ModifyContractData(tx.To, string(data)))
+			block.commitStorage(roots)
+		}
+	}
 	return &receipt
 }
 
@@ -162,23 +201,25 @@ func (block *Block) NormalTransfer(tx userevent.Transaction) *userevent.Transact
 	txs = append(txs, *subTx)
 	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
 	receipt.SubTransactions = txs
-	block.GetHeader().NewSubTransaction(txs)
+	success := block.GetHeader().NewSubTransaction(txs)
+	// 升级之前转账的回执不记录子交易的执行结果, 重放历史区块时回执保持不变
+	if param.IsActive(param.FORK_BIGINT, block.GetHeader().Height) {
+		receipt.Success = success
+	}
 	return &receipt
 }
 
 func (block *Block) DeployContract(tx userevent.Transaction) *userevent.TransactionReceipt {
+	_vm := block.newVM(tx)
+	return block.chargeGas(tx, _vm, block.deployContract(tx, _vm))
+}
+
+func (block *Block) deployContract(tx userevent.Transaction, _vm *vm.Otto) *userevent.TransactionReceipt {
 	account, _ := block.GetHeader().GetAccount(tx.From)
 
-	_vm := vm.NewVM(block.GetHeader(), db.GetDBInst())
-	contractData, err := _vm.InitContractWithTimeout([]byte(tx.Data), VM_CALL_TIMEOUT)
+	contractData, err := _vm.InitContractWithTimeout([]byte(tx.Data), block.vmCallTimeout())
 	if err != nil {
-		if err == vm.TIMEOUT_ERROR {
-			receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_CONTRACT_TIMEOUT)
-			return &receipt
-		} else {
-			receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_INIT_CONTRACT_ACCOUNT_FAIL)
-			return &receipt
-		}
+		return vmFailReceipt(tx, err, userevent.FailType_INIT_CONTRACT_ACCOUNT_FAIL)
 	}
 
 	contractHash := crypto.Sha3_256([]byte(tx.Data))
@@ -190,13 +231,17 @@ func (block *Block) DeployContract(tx userevent.Transaction) *userevent.Transact
 		account.Contracts = make(map[string]types.ContractAccount)
 	}
 
-	if tx.Amount > 0 && tx.TokenAddress == "" && account.GetAmount() >= tx.Amount {
-		account.Amount -= tx.Amount
-		contractAccount.Amount += tx.Amount
+	if tx.Amount.Sign() > 0 && tx.TokenAddress == "" && account.GetAmount().Cmp(tx.Amount) >= 0 {
+		account.Amount = account.Amount.Sub(tx.Amount)
+		contractAccount.Amount = contractAccount.Amount.Add(tx.Amount)
 	}
 
 	account.Contracts[hex.EncodeToString(addr)] = *contractAccount
 	logErr(block.GetHeader().StatTree.MustInsert(account.Address, account.ToBytes()))
+	block.GetHeader().emitEvent(EVENT_CONTRACT_DEPLOYED, tx.TxId(), ContractEvent{
+		Address:  append(append([]byte{}, account.Address...), addr...),
+		CodeHash: contractHash,
+	})
 
 	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
 	return &receipt
@@ -236,11 +281,10 @@ func (block *Block) upgradeContract(tx userevent.Transaction) *userevent.Transac
 		return &receipt
 	}
 
-	_vm := vm.NewVM(block.GetHeader(), db.GetDBInst())
-	contractData, err := _vm.UpgradeContract([]byte(tx.Data), &contractAccount.ContractData, VM_CALL_TIMEOUT)
+	_vm := block.newVM(tx)
+	contractData, err := _vm.UpgradeContract([]byte(tx.Data), &contractAccount.ContractData, block.vmCallTimeout())
 	if err != nil {
-		receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_CONTRACT_ERROR)
-		return &receipt
+		return block.chargeGas(tx, _vm, vmFailReceipt(tx, err, userevent.FailType_CONTRACT_ERROR))
 	}
 
 	contractAccount.CodeHash = crypto.Sha3_256([]byte(tx.Data))
@@ -250,15 +294,19 @@ func (block *Block) upgradeContract(tx userevent.Transaction) *userevent.Transac
 	account.Contracts[hex.EncodeToString(tx.To[32:64])] = contractAccount
 
 	logErr(block.GetHeader().StatTree.MustInsert(tx.From, account.ToBytes()))
+	block.GetHeader().emitEvent(EVENT_CONTRACT_UPGRADED, tx.TxId(), ContractEvent{
+		Address:  tx.To[:64],
+		CodeHash: contractAccount.CodeHash,
+	})
 
 	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
-	return &receipt
+	return block.chargeGas(tx, _vm, &receipt)
 }
 
 func (block *Block) CheckSubTransaction(tx userevent.Transaction, subTxs userevent.SubTransactions) bool {
 	if len(subTxs) > 0 {
 		for _, subTx := range subTxs {
-			if !bytes.Equal(subTx.From, tx.To) || subTx.Amount <= 0 {
+			if !bytes.Equal(subTx.From, tx.To) || subTx.Amount.Sign() <= 0 || !block.GetHeader().AllowAmount(subTx.Amount) {
 				return false
 			}
 			subTx.Parent = tx.TxId()
@@ -323,3 +371,16 @@ func NewBlock_V2(last Header, time int64, peer types.Peer) *Block {
 		TransactionReceipts: make([]userevent.TransactionReceipt, 0),
 	}
 }
+
+// 按照合约地址的顺序写入合约存储的根节点, 保证各个节点的状态树一致
+func (block *Block) commitStorage(roots map[string][]byte) {
+	addresses := make([]string, 0, len(roots))
+	for address := range roots {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		addr, _ := hex.DecodeString(address)
+		logErr(block.GetHeader().ModifyContractStorage(addr, roots[address]))
+	}
+}
@@ -1,13 +1,18 @@
 package blockchain
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"time"
 
+	"github.com/EducationEKT/EKT/contract"
 	"github.com/EducationEKT/EKT/core/types"
 	"github.com/EducationEKT/EKT/core/userevent"
 	"github.com/EducationEKT/EKT/ctxlog"
 	"github.com/EducationEKT/EKT/db"
 	"github.com/EducationEKT/EKT/log"
+	"github.com/EducationEKT/EKT/param"
 	"github.com/EducationEKT/EKT/pool"
 	"github.com/EducationEKT/EKT/schema"
 )
@@ -16,6 +21,12 @@ const (
 	BackboneBlockInterval = 3 * time.Second
 )
 
+var (
+	ErrInvalidBlock = errors.New("invalid block")
+	// 校验时本地执行合约超时, 和区块是否有效无关
+	ErrValidateTimeout = errors.New("validate block timeout")
+)
+
 type BlockChain struct {
 	ChainId       int64
 	header        Header
@@ -35,6 +46,7 @@ func (chain *BlockChain) LastHeader() Header {
 }
 
 func (chain *BlockChain) SetLastHeader(header Header) {
+	header.chainId = chain.ChainId
 	chain.header = header
 	chain.currentHeight = header.Height
 }
@@ -43,27 +55,87 @@ func (chain *BlockChain) GetLastHeight() int64 {
 	return chain.currentHeight
 }
 
+// 打包交易的时间为出块间隔的2/3，剩余的时间用于广播和投票
 func (chain *BlockChain) PackTime(block *Block) time.Duration {
-	return time.Duration(block.GetHeader().Timestamp+2000-time.Now().UnixNano()/1e6) * 1e6
+	interval := block.GetHeader().ChainParams().BlockInterval
+	return time.Duration(block.GetHeader().Timestamp+interval*2/3-time.Now().UnixNano()/1e6) * 1e6
+}
+
+// 最新区块生效的共识参数
+func (chain *BlockChain) ChainParams() ChainParams {
+	return chain.LastHeader().ChainParams()
+}
+
+func (chain *BlockChain) BlockLimits() BlockLimits {
+	return chain.ChainParams().BlockLimits
 }
 
 func (chain *BlockChain) PackTransaction(clog *ctxlog.ContextLog, block *Block) {
 	defer block.Finish()
+	params := block.GetHeader().ChainParams()
+	limits := params.BlockLimits
 	t := chain.PackTime(block)
 	eventTimeout := time.After(t)
 
 	start := time.Now().UnixNano()
-	numTx := 0
+	header := block.GetHeader()
+	numTx, bodySize, vmCount, vmTime := 0, 0, 0, time.Duration(0)
+	immature := make([]*userevent.Transaction, 0)
+	// 有尚未生效的交易的发送方, 它之后的交易nonce不连续, 一起放回交易池
+	waiting := make(map[string]bool)
 	for {
 		flag := false
 		select {
 		case <-eventTimeout:
 			flag = true
 		default:
-			txs := chain.Pool.Pop(20)
+			size := 20
+			if left := limits.MaxTxCount - numTx; left < size {
+				size = left
+			}
+			txs := chain.Pool.Pop(size)
 			if len(txs) > 0 {
-				for _, tx := range txs {
+				for i, tx := range txs {
+					// 同一个用户之前的交易被删除时, 这个交易也已经从交易池中删除
+					if chain.Pool.GetTx(tx.TxId()) == nil {
+						continue
+					}
+					// 已经过期的交易从交易池中删除，尚未生效的交易等打包结束之后放回交易池
+					if tx.Expired(header.Height, header.Timestamp) {
+						chain.Pool.Drop(tx)
+						continue
+					}
+					if sender := hex.EncodeToString(tx.From); waiting[sender] || !tx.Mature(header.Height, header.Timestamp) {
+						waiting[sender] = true
+						immature = append(immature, tx)
+						continue
+					}
+					// 超出区块大小或者合约交易数量的限制时，剩余的交易放回交易池等待下一个区块
+					// 合约的执行时间只在本地打包时限制, 保证能在出块间隔内完成打包
+					isVMTx := IsVMTransaction(*tx)
+					txSize := len(tx.Bytes())
+					if bodySize+txSize > limits.MaxBodyBytes ||
+						(isVMTx && (vmCount >= limits.MaxVMTxCount || vmTime+params.VMTimeout() > limits.VMTimeLimit())) {
+						chain.Pool.Restore(txs[i:]...)
+						flag = true
+						break
+					}
+					snapshot := header.snapshot()
+					txStart := time.Now()
 					receipt := block.NewTransaction(*tx)
+					if block.vmTimedOut(receipt) {
+						// 超时只是本地的保护, 撤销这个交易并结束打包, 这个交易在本节点上无法执行, 从交易池中删除
+						header.revert(snapshot)
+						chain.Pool.Drop(tx)
+						chain.Pool.Restore(txs[i+1:]...)
+						flag = true
+						break
+					}
+					if isVMTx {
+						vmCount++
+						vmTime += time.Since(txStart)
+					}
+					bodySize += txSize
 					log.LogErr(block.Header.TxRoot.MustInsert(tx.TxId(), tx.Bytes()))
 					log.LogErr(block.Header.ReceiptRoot.MustInsert(tx.TxId(), receipt.Bytes()))
 					receiptDetail := userevent.ReceiptDetail{
@@ -74,16 +146,20 @@ func (chain *BlockChain) PackTransaction(clog *ctxlog.ContextLog, block *Block)
 					log.LogErr(db.GetDBInst().Set(schema.GetReceiptByTxHashKey(chain.ChainId, tx.TransactionId()), receiptDetail.Bytes()))
 					block.Transactions = append(block.Transactions, *tx)
 					block.TransactionReceipts = append(block.TransactionReceipts, *receipt)
+					numTx++
 				}
-				numTx += len(txs)
 			} else {
 				chain.Pool.Promote(*block.GetHeader().StatTree)
 			}
+			if numTx >= limits.MaxTxCount {
+				flag = true
+			}
 		}
 		if flag {
 			break
 		}
 	}
+	chain.Pool.Restore(immature...)
 
 	end := time.Now().UnixNano()
 	log.Debug("Total tx: %d, Total time: %d ns, TPS: %d. \n", numTx, end-start, numTx*1e9/int(end-start))
@@ -92,10 +168,14 @@ func (chain *BlockChain) PackTransaction(clog *ctxlog.ContextLog, block *Block)
 // 当区块写入区块时，notify交易池，一些nonce比较大的交易可以进行打包
 func (chain *BlockChain) NotifyPool(txs []userevent.Transaction) {
 	chain.Pool.Notify(txs)
+	chain.Pool.DropExpired(chain.currentHeight+1, time.Now().UnixNano()/1e6)
 }
 
 func (chain *BlockChain) NewTransaction(tx *userevent.Transaction) bool {
 	block := chain.LastHeader()
+	if !tx.Supported(block.Height+1) || tx.Expired(block.Height+1, time.Now().UnixNano()/1e6) {
+		return false
+	}
 	account, err := block.GetAccount(tx.GetFrom())
 	if err != nil || account == nil {
 		if tx.GetNonce() != 1 {
@@ -103,28 +183,71 @@ func (chain *BlockChain) NewTransaction(tx *userevent.Transaction) bool {
 		}
 		account = types.NewAccount(tx.From)
 	}
-	if account.GetNonce() >= tx.GetNonce() {
+	if account.GetNonce() >= tx.GetNonce() || !userevent.CheckAuthorization(*tx, account) {
 		return false
 	}
 	chain.Pool.Park(tx, account.GetNonce())
 	return true
 }
 
-func (chain *BlockChain) ValidateBlock(next Block) bool {
+// 预先读取交易发送方轮换之后的公钥hash, 供签名校验器判断签名人
+// 同一批交易中的公钥轮换也计入, 签名人是否等于执行到这笔交易时的公钥由CheckAuthorization判断
+func (chain *BlockChain) AuthKeys(txs []userevent.Transaction) userevent.AuthKeys {
+	header := chain.LastHeader()
+	authKeys := make(userevent.AuthKeys)
+	fetched := make(map[string]bool)
+	for _, tx := range txs {
+		if from := hex.EncodeToString(tx.From); !fetched[from] {
+			fetched[from] = true
+			if account, err := header.GetAccount(tx.From); err == nil && len(account.AuthKey) != 0 {
+				authKeys.Add(tx.From, account.AuthKey)
+			}
+		}
+		if len(tx.To) == types.ContractAddressLength && hex.EncodeToString(tx.To) == contract.SYSTEM_AUTHOR+contract.KEY_ROTATION_CONTRACT {
+			var rotation KeyRotation
+			if json.Unmarshal([]byte(tx.Data), &rotation) == nil && len(rotation.AuthKey) == types.AccountAddressLength {
+				authKeys.Add(tx.From, rotation.AuthKey)
+			}
+		}
+	}
+	return authKeys
+}
+
+// 校验其他节点打包的区块, 返回ErrValidateTimeout时区块不一定无效, 需要稍后重试
+func (chain *BlockChain) ValidateBlock(next Block) error {
 	lastHeader := chain.LastHeader()
 	newBlock := CreateBlock(lastHeader, next.GetHeader().Timestamp, next.Miner)
+	// 激活gas计量之后合约的执行结果只由gas决定, 校验时使用整个出块间隔作为本地的超时保护
+	if param.IsActive(param.FORK_VM_GAS, newBlock.GetHeader().Height) {
+		newBlock.vmTimeout = newBlock.GetHeader().ChainParams().Interval()
+	}
+	// 区块头的版本由升级计划决定, 和本地重新执行的区块版本不一致时拒绝
+	if next.GetHeader().Version != newBlock.GetHeader().Version {
+		return ErrInvalidBlock
+	}
 	receipts := next.GetTxReceipts()
-	for i, tx := range next.GetTransactions() {
+	transactions := next.GetTransactions()
+
+	params := newBlock.GetHeader().ChainParams()
+	limits := params.BlockLimits
+	// 区块大小的限制从升级高度开始校验, 同步之前的区块时保持旧的规则, 合约交易的数量是确定的上限, 不依赖校验节点执行合约的时间
+	if param.IsActive(param.FORK_BLOCK_LIMITS, newBlock.GetHeader().Height) && !limits.CheckBody(transactions) {
+		return ErrInvalidBlock
+	}
+
+	// 在执行交易之前并行校验所有交易的签名，交易池中的交易已经校验过会直接命中缓存
+	if !userevent.DefaultVerifier.VerifyBatch(transactions, chain.AuthKeys(transactions)) {
+		return ErrInvalidBlock
+	}
+
+	for i, tx := range transactions {
 		if chain.Pool.GetTx(tx.TxId()) == nil {
-			if !userevent.ValidateTransaction(tx) {
-				return false
-			} else {
-				logErr(db.GetDBInst().Set(tx.TxId(), tx.Bytes()))
-			}
+			logErr(db.GetDBInst().Set(tx.TxId(), tx.Bytes()))
 		}
 		log.LogErr(newBlock.GetHeader().TxRoot.MustInsert(tx.TxId(), tx.Bytes()))
 		var receipt *userevent.TransactionReceipt
-		if len(tx.To) == types.ContractAddressLength {
+		// 激活gas计量之前失败的合约调用直接使用出块节点的回执, 之后所有交易都在本地重新执行
+		if len(tx.To) == types.ContractAddressLength && !param.IsActive(param.FORK_VM_GAS, newBlock.GetHeader().Height) {
 			_receipt := receipts[i]
 			if !_receipt.Success {
 				newBlock.GetHeader().CheckFromAndBurnGas(tx)
@@ -141,6 +264,9 @@ func (chain *BlockChain) ValidateBlock(next Block) bool {
 			}
 		}
 		receipt = newBlock.NewTransaction(tx)
+		if newBlock.vmTimedOut(receipt) {
+			return ErrInvalidBlock
+		}
 		log.LogErr(newBlock.GetHeader().ReceiptRoot.MustInsert(tx.TxId(), receipt.Bytes()))
 		receiptDetail := userevent.ReceiptDetail{
 			Receipt:     *receipt,
@@ -152,5 +278,8 @@ func (chain *BlockChain) ValidateBlock(next Block) bool {
 		newBlock.TransactionReceipts = append(newBlock.TransactionReceipts, *receipt)
 	}
 	newBlock.Finish()
-	return newBlock.GetHeader().Equal(*next.GetHeader())
+	if !newBlock.GetHeader().Equal(*next.GetHeader()) {
+		return ErrInvalidBlock
+	}
+	return nil
 }
@@ -0,0 +1,204 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/EducationEKT/EKT/bancor"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/crypto"
+)
+
+const (
+	CONVERTER_OP_FUND    = "fund"
+	CONVERTER_OP_CONVERT = "convert"
+
+	// 一次兑换最多经过的兑换合约数量
+	MaxConversionSteps = 4
+)
+
+type ConverterDeploy struct {
+	SmartToken string             `json:"smartToken"`
+	Supply     types.Amount       `json:"supply"`
+	Connectors []bancor.Connector `json:"connectors"`
+}
+
+// 兑换路径中的一步, 在Converter中把上一步得到的Token兑换为To
+type ConversionStep struct {
+	Converter types.HexBytes `json:"converter"`
+	To        string         `json:"to"`
+}
+
+type ConverterCallParam struct {
+	Op        string           `json:"op"`
+	To        string           `json:"to"`
+	MinReturn types.Amount     `json:"minReturn"`
+	Path      []ConversionStep `json:"path,omitempty"` // 在当前兑换合约之后继续兑换的路径
+}
+
+// 兑换合约部署在智能Token发行人的账户下, 每个智能Token只能有一个兑换合约
+func ConverterAddress(issuer []byte, smartToken string) []byte {
+	return append(append([]byte{}, issuer...), crypto.Sha3_256([]byte("bancor:"+smartToken))...)
+}
+
+// 智能Token的发行人部署Bancor兑换合约, 部署之后需要通过fund向每个连接器存入Token并存入用于出售的智能Token
+func (block *Block) DeployConverter(tx userevent.Transaction) *userevent.TransactionReceipt {
+	var param ConverterDeploy
+	if err := json.Unmarshal([]byte(tx.Data), &param); err != nil || !tx.Amount.IsZero() {
+		return systemRefused(tx)
+	}
+	header := block.GetHeader()
+	tokenAddress, err := hex.DecodeString(param.SmartToken)
+	if err != nil {
+		return systemRefused(tx)
+	}
+	token, err := header.GetToken(tokenAddress)
+	if err != nil || token == nil || !token.IsIssuer(tx.From) {
+		return systemRefused(tx)
+	}
+	for _, connector := range param.Connectors {
+		if !header.ExistToken(connector.TokenAddress) {
+			return systemRefused(tx)
+		}
+	}
+	converter, err := bancor.NewConverter(param.SmartToken, param.Supply, param.Connectors)
+	if err != nil {
+		return systemRefused(tx)
+	}
+
+	account, err := header.GetAccount(tx.From)
+	if err != nil || account == nil {
+		return systemRefused(tx)
+	}
+	address := ConverterAddress(tx.From, param.SmartToken)
+	if _, exist := account.Contracts[hex.EncodeToString(address[32:])]; exist {
+		return systemRefused(tx)
+	}
+	contractData := types.ContractData{
+		Prop: types.ContractProp{
+			Name:   token.Symbol + " Bancor converter",
+			Author: hex.EncodeToString(tx.From),
+		},
+		Contract: string(converter.Data()),
+	}
+	contractAccount := types.NewContractAccount(address[32:], nil, contractData)
+	contractAccount.Native = types.NativeBancor
+	if account.Contracts == nil {
+		account.Contracts = make(map[string]types.ContractAccount)
+	}
+	account.Contracts[hex.EncodeToString(address[32:])] = *contractAccount
+	logErr(header.StatTree.MustInsert(account.Address, account.ToBytes()))
+	header.emitEvent(EVENT_CONTRACT_DEPLOYED, tx.TxId(), ContractEvent{
+		Address: address,
+		Native:  types.NativeBancor,
+	})
+
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	return &receipt
+}
+
+func (header Header) GetConverter(address []byte) (*bancor.Converter, error) {
+	if len(address) != types.ContractAddressLength {
+		return nil, bancor.ErrInvalidConverter
+	}
+	account, err := header.GetAccount(address[:32])
+	if err != nil || account == nil {
+		return nil, bancor.ErrInvalidConverter
+	}
+	contractAccount, exist := account.Contracts[hex.EncodeToString(address[32:])]
+	if !exist || contractAccount.Native != types.NativeBancor {
+		return nil, bancor.ErrInvalidConverter
+	}
+	var converter bancor.Converter
+	if !converter.Recover([]byte(contractAccount.ContractData.Contract)) {
+		return nil, bancor.ErrInvalidConverter
+	}
+	return &converter, nil
+}
+
+func (header *Header) saveConverter(address []byte, converter *bancor.Converter) {
+	account, err := header.GetAccount(address[:32])
+	if err != nil || account == nil {
+		return
+	}
+	contractAccount := account.Contracts[hex.EncodeToString(address[32:])]
+	contractAccount.ContractData.Contract = string(converter.Data())
+	account.Contracts[hex.EncodeToString(address[32:])] = contractAccount
+	logErr(header.StatTree.MustInsert(account.Address, account.ToBytes()))
+}
+
+// 调用用户部署的兑换合约
+// fund: 合约所有人存入连接器Token或者智能Token
+// convert: 把tx.TokenAddress兑换为To, 可以通过Path继续在其他兑换合约中兑换, 最终结果转给交易发送方
+func (block *Block) ConverterCall(tx userevent.Transaction) *userevent.TransactionReceipt {
+	var param ConverterCallParam
+	if err := json.Unmarshal([]byte(tx.Data), &param); err != nil || tx.Amount.Sign() <= 0 {
+		return systemRefused(tx)
+	}
+	header := block.GetHeader()
+	converters := make(map[string]*bancor.Converter)
+	txs := userevent.SubTransactions{*userevent.NewSubTransaction(tx.TxId(), tx.From, tx.To, tx.Amount, tx.Data, tx.TokenAddress)}
+
+	switch param.Op {
+	case CONVERTER_OP_FUND:
+		converter, err := header.GetConverter(tx.To)
+		if err != nil || !bytes.Equal(tx.From, tx.To[:32]) || !converter.Supports(tx.TokenAddress) {
+			return systemRefused(tx)
+		}
+		if tx.TokenAddress != converter.SmartTokenAddress && converter.Fund(tx.TokenAddress, tx.Amount) != nil {
+			return systemRefused(tx)
+		}
+		converters[hex.EncodeToString(tx.To)] = converter
+	case CONVERTER_OP_CONVERT:
+		steps := append([]ConversionStep{{Converter: tx.To, To: param.To}}, param.Path...)
+		if len(steps) > MaxConversionSteps {
+			return systemRefused(tx)
+		}
+		from, tokenAddress, amount := []byte(tx.To), tx.TokenAddress, tx.Amount
+		for i, step := range steps {
+			converter, exist := converters[hex.EncodeToString(step.Converter)]
+			if !exist {
+				var err error
+				if converter, err = header.GetConverter(step.Converter); err != nil {
+					return systemRefused(tx)
+				}
+				converters[hex.EncodeToString(step.Converter)] = converter
+			}
+			// 上一个兑换合约把得到的Token转给下一个兑换合约
+			if i > 0 {
+				txs = append(txs, *userevent.NewSubTransaction(tx.TxId(), from, step.Converter, amount, "", tokenAddress))
+			}
+			result, err := converter.Convert(tokenAddress, step.To, amount)
+			if err != nil || result.Sign() <= 0 {
+				return systemRefused(tx)
+			}
+			from, tokenAddress, amount = step.Converter, step.To, result
+		}
+		if amount.Cmp(param.MinReturn) < 0 {
+			return systemRefused(tx)
+		}
+		txs = append(txs, *userevent.NewSubTransaction(tx.TxId(), from, tx.From, amount, "From bancor converter", tokenAddress))
+	default:
+		return systemRefused(tx)
+	}
+
+	if !header.NewSubTransaction(txs) {
+		receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_CHECK_CONTRACT_SUBTX_ERROR)
+		return &receipt
+	}
+	addresses := make([]string, 0, len(converters))
+	for address := range converters {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		contractAddress, _ := hex.DecodeString(address)
+		header.saveConverter(contractAddress, converters[address])
+	}
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	receipt.SubTransactions = txs
+	return &receipt
+}
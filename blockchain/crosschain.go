@@ -0,0 +1,444 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/EducationEKT/EKT/MPTPlus"
+	"github.com/EducationEKT/EKT/contract"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/param"
+)
+
+const (
+	CROSSCHAIN_OP_REGIST  = "regist"
+	CROSSCHAIN_OP_SEND    = "send"
+	CROSSCHAIN_OP_RECEIVE = "receive"
+
+	CROSSCHAIN_STATUS_PENDING = "pending"
+	CROSSCHAIN_STATUS_ACTIVE  = "active"
+
+	EVENT_CROSSCHAIN_REGISTERED = "crossChainRegistered"
+	EVENT_CROSSCHAIN_SENT       = "crossChainSent"
+	EVENT_CROSSCHAIN_RECEIVED   = "crossChainReceived"
+)
+
+var (
+	ErrCrossChainNotEnabled  = errors.New("crosschain is not enabled")
+	ErrCrossChainReceived    = errors.New("crosschain transfer already received")
+	ErrInvalidCrossChainVote = errors.New("invalid crosschain votes")
+)
+
+// 在EKT中注册的外部公链和Token, 所有委托人都同意注册之后才可以进行跨链交易
+type CrossChainRegistration struct {
+	PublicChainId string           `json:"publicChainId"`
+	TokenId       string           `json:"tokenId"`
+	TokenAddress  string           `json:"tokenAddress"` // EKT中对应的Token地址
+	OpCodeId      string           `json:"opCodeId"`
+	Delegates     []types.HexBytes `json:"delegates"`              // 外部公链的委托人地址, 用来验证发来的区块头
+	LocalChainId  string           `json:"localChainId,omitempty"` // 外部公链发来的跨链交易中当前链的publicChainId, 为空时是DefaultPublicChainId
+	BlockchainId  int64            `json:"blockchainId,omitempty"` // 外部公链区块投票中的链id, 为0时是主链
+	Approvals     []types.HexBytes `json:"approvals"`
+	Status        string           `json:"status"`
+}
+
+// 校验跨链消息只使用链上的注册记录, 不依赖节点本地的配置
+func (registration CrossChainRegistration) localChainId() string {
+	if registration.LocalChainId == "" {
+		return param.DefaultPublicChainId
+	}
+	return registration.LocalChainId
+}
+
+func (registration CrossChainRegistration) blockchainId() int64 {
+	if registration.BlockchainId == 0 {
+		return param.MainChainId
+	}
+	return registration.BlockchainId
+}
+
+func (registration CrossChainRegistration) Bytes() []byte {
+	data, _ := json.Marshal(registration)
+	return data
+}
+
+func (registration CrossChainRegistration) Approved(address []byte) bool {
+	for _, approval := range registration.Approvals {
+		if bytes.Equal(approval, address) {
+			return true
+		}
+	}
+	return false
+}
+
+func (registration CrossChainRegistration) IsDelegate(account string) bool {
+	for _, delegate := range registration.Delegates {
+		if strings.EqualFold(hex.EncodeToString(delegate), account) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameDelegates(a, b []types.HexBytes) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// 跨链交易的参数
+// 注册时需要PublicChainId、TokenId、OpCodeId和外部公链的Delegates
+// 发送时需要目标链的PublicChainId和To, TokenId不传时使用交易的TokenAddress
+// 接收时PublicChainId为发送方公链, Message为发送方中继提交的跨链消息
+type CrossChainOp struct {
+	Op            string             `json:"op"`
+	PublicChainId string             `json:"publicChainId"`
+	TokenId       string             `json:"tokenId,omitempty"`
+	OpCodeId      string             `json:"opCodeId,omitempty"`
+	Delegates     []types.HexBytes   `json:"delegates,omitempty"`
+	LocalChainId  string             `json:"localChainId,omitempty"`
+	BlockchainId  int64              `json:"blockchainId,omitempty"`
+	To            types.HexBytes     `json:"to,omitempty"` // 目标链上的接收地址
+	Message       *CrossChainMessage `json:"message,omitempty"`
+}
+
+func (op CrossChainOp) tokenId(tokenAddress string) string {
+	if op.TokenId != "" {
+		return op.TokenId
+	}
+	return tokenIdOf(tokenAddress)
+}
+
+// 跨链转账的记录, 发送和接收时作为系统事件保存
+type CrossChainTransfer struct {
+	TxId          types.HexBytes `json:"txId,omitempty"` // 发送方链上的交易id
+	PublicChainId string         `json:"publicChainId"`
+	From          types.HexBytes `json:"from"`
+	To            types.HexBytes `json:"to"`
+	TokenAddress  string         `json:"tokenAddress"`
+	Amount        types.Amount   `json:"amount"`
+}
+
+func CrossChainKey(publicChainId, tokenAddress string) []byte {
+	return crypto.Sha3_256([]byte("crosschain:" + publicChainId + ":" + tokenAddress))
+}
+
+// 发往外部公链的资产锁定在以公链id生成的地址中, 这个地址没有对应的私钥
+func CrossChainEscrowAddress(publicChainId string) []byte {
+	return crypto.Sha3_256([]byte("crosschain:" + publicChainId))
+}
+
+// tokenId可以是EKT、GAS、已发行Token的symbol或者Token地址
+func (header Header) ResolveTokenId(tokenId string) (string, bool) {
+	switch strings.ToUpper(tokenId) {
+	case "EKT":
+		return types.EKTAddress, true
+	case "GAS":
+		return types.GasAddress, true
+	}
+	if address, err := header.GetTokenAddress(tokenId); err == nil && len(address) > 0 {
+		return hex.EncodeToString(address), true
+	}
+	if tokenId != "" && header.ExistToken(tokenId) {
+		return tokenId, true
+	}
+	return "", false
+}
+
+func (header Header) GetCrossChainRegistration(publicChainId, tokenId string) (*CrossChainRegistration, error) {
+	if header.ChainStat == nil {
+		return nil, ErrCrossChainNotEnabled
+	}
+	tokenAddress, exist := header.ResolveTokenId(tokenId)
+	if !exist {
+		return nil, errors.New("token not found")
+	}
+	var registration CrossChainRegistration
+	if err := header.ChainStat.GetInterfaceValue(CrossChainKey(publicChainId, tokenAddress), &registration); err != nil {
+		return nil, err
+	}
+	return &registration, nil
+}
+
+// 外部公链和Token是否已经完成注册
+func (header Header) CrossChainActive(publicChainId, tokenId string) bool {
+	registration, err := header.GetCrossChainRegistration(publicChainId, tokenId)
+	return err == nil && registration.Status == CROSSCHAIN_STATUS_ACTIVE
+}
+
+func (block *Block) CrossChain(tx userevent.Transaction) *userevent.TransactionReceipt {
+	var op CrossChainOp
+	if err := json.Unmarshal([]byte(tx.Data), &op); err != nil || op.PublicChainId == "" {
+		return systemRefused(tx)
+	}
+	if block.GetHeader().ChainStat == nil {
+		return systemRefused(tx)
+	}
+	switch op.Op {
+	case CROSSCHAIN_OP_REGIST:
+		return block.registCrossChain(tx, op)
+	case CROSSCHAIN_OP_SEND:
+		return block.sendCrossChain(tx, op)
+	case CROSSCHAIN_OP_RECEIVE:
+		return block.receiveCrossChain(tx, op)
+	}
+	return systemRefused(tx)
+}
+
+// 委托人同意注册外部公链, 第一个委托人的交易创建注册记录, 全部委托人同意之后生效
+func (block *Block) registCrossChain(tx userevent.Transaction, op CrossChainOp) *userevent.TransactionReceipt {
+	header := block.GetHeader()
+	if !tx.Amount.IsZero() || op.OpCodeId == "" || !header.IsDelegate(tx.From) {
+		return systemRefused(tx)
+	}
+	tokenAddress, exist := header.ResolveTokenId(op.TokenId)
+	if !exist {
+		return systemRefused(tx)
+	}
+	registration, err := header.GetCrossChainRegistration(op.PublicChainId, op.TokenId)
+	if err != nil {
+		registration = &CrossChainRegistration{
+			PublicChainId: op.PublicChainId,
+			TokenId:       op.TokenId,
+			TokenAddress:  tokenAddress,
+			OpCodeId:      op.OpCodeId,
+			Delegates:     op.Delegates,
+			LocalChainId:  op.LocalChainId,
+			BlockchainId:  op.BlockchainId,
+			Approvals:     make([]types.HexBytes, 0),
+			Status:        CROSSCHAIN_STATUS_PENDING,
+		}
+	}
+	if registration.Status != CROSSCHAIN_STATUS_PENDING || registration.OpCodeId != op.OpCodeId ||
+		registration.LocalChainId != op.LocalChainId || registration.BlockchainId != op.BlockchainId ||
+		!sameDelegates(registration.Delegates, op.Delegates) || registration.Approved(tx.From) {
+		return systemRefused(tx)
+	}
+	registration.Approvals = append(registration.Approvals, tx.From)
+	if len(registration.Approvals) >= len(header.Delegates()) {
+		registration.Status = CROSSCHAIN_STATUS_ACTIVE
+		header.emitEvent(EVENT_CROSSCHAIN_REGISTERED, tx.TxId(), registration)
+	}
+	logErr(header.ChainStat.MustInsert(CrossChainKey(registration.PublicChainId, tokenAddress), registration.Bytes()))
+
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	return &receipt
+}
+
+func (block *Block) sendCrossChain(tx userevent.Transaction, op CrossChainOp) *userevent.TransactionReceipt {
+	if receipt := block.lockCrossChain(tx, op); receipt != nil {
+		return receipt
+	}
+	return systemRefused(tx)
+}
+
+// 转账到外部地址, 和调用跨链系统合约的send一样锁定在目标公链的锁定地址中
+func (block *Block) ExternalTransfer(tx userevent.Transaction) *userevent.TransactionReceipt {
+	if op, ok := CrossChainSendOf(tx); ok {
+		if receipt := block.lockCrossChain(tx, *op); receipt != nil {
+			return receipt
+		}
+	}
+	receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_INVALID_ADDRESS)
+	return &receipt
+}
+
+// 跨链发送的两种形式: 调用跨链系统合约的send, 或者直接转账到外部地址
+func CrossChainSendOf(tx userevent.Transaction) (*CrossChainOp, bool) {
+	if len(tx.To) == types.ExternalAddressLength {
+		external, err := types.DecodeExternalAddress(tx.To)
+		if err != nil {
+			return nil, false
+		}
+		return &CrossChainOp{Op: CROSSCHAIN_OP_SEND, PublicChainId: external.PublicChainId, To: external.Address}, true
+	}
+	if hex.EncodeToString(tx.To) != contract.SYSTEM_AUTHOR+contract.CROSSCHAIN_CONTRACT {
+		return nil, false
+	}
+	var op CrossChainOp
+	if err := json.Unmarshal([]byte(tx.Data), &op); err != nil || op.Op != CROSSCHAIN_OP_SEND {
+		return nil, false
+	}
+	return &op, true
+}
+
+// 跨链发送, 交易的Amount转入目标公链的锁定地址, 由目标链处理跨链事件之后在目标链上释放, 没有注册时返回nil
+func (block *Block) lockCrossChain(tx userevent.Transaction, op CrossChainOp) *userevent.TransactionReceipt {
+	header := block.GetHeader()
+	if header.ChainStat == nil || tx.Amount.Sign() <= 0 || len(op.To) == 0 {
+		return nil
+	}
+	registration, err := header.GetCrossChainRegistration(op.PublicChainId, op.tokenId(tx.TokenAddress))
+	if err != nil || registration.Status != CROSSCHAIN_STATUS_ACTIVE || registration.TokenAddress != tx.TokenAddress {
+		return nil
+	}
+	escrow := CrossChainEscrowAddress(op.PublicChainId)
+	subTx := userevent.NewSubTransaction(tx.TxId(), tx.From, escrow, tx.Amount, "", tx.TokenAddress)
+	txs := userevent.SubTransactions{*subTx}
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	receipt.Success = header.NewSubTransaction(txs)
+	receipt.SubTransactions = txs
+	if receipt.Success {
+		header.emitEvent(EVENT_CROSSCHAIN_SENT, tx.TxId(), CrossChainTransfer{
+			TxId:          tx.TxId(),
+			PublicChainId: op.PublicChainId,
+			From:          tx.From,
+			To:            op.To,
+			TokenAddress:  tx.TokenAddress,
+			Amount:        tx.Amount,
+		})
+	}
+	return &receipt
+}
+
+// 交易中EKT的TokenAddress为空, 查询注册记录时使用EKT的symbol
+func tokenIdOf(tokenAddress string) string {
+	if tokenAddress == types.EKTAddress {
+		return "EKT"
+	}
+	return tokenAddress
+}
+
+// 接收外部公链的跨链转账, 消息验证通过之后从发送方公链的锁定地址转给接收人
+func (block *Block) receiveCrossChain(tx userevent.Transaction, op CrossChainOp) *userevent.TransactionReceipt {
+	header := block.GetHeader()
+	if !tx.Amount.IsZero() || op.Message == nil || op.Message.SourceChainId != op.PublicChainId {
+		return systemRefused(tx)
+	}
+	transfer, err := header.VerifyCrossChainMessage(*op.Message)
+	if err != nil || len(transfer.To) != types.AccountAddressLength {
+		return systemRefused(tx)
+	}
+	escrow := CrossChainEscrowAddress(transfer.PublicChainId)
+	subTx := userevent.NewSubTransaction(tx.TxId(), escrow, transfer.To, transfer.Amount, "", transfer.TokenAddress)
+	txs := userevent.SubTransactions{*subTx}
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	receipt.Success = header.NewSubTransaction(txs)
+	receipt.SubTransactions = txs
+	if receipt.Success {
+		data, _ := json.Marshal(transfer)
+		logErr(header.ChainStat.MustInsert(crossChainReceivedKey(transfer.PublicChainId, transfer.TxId), data))
+		header.emitEvent(EVENT_CROSSCHAIN_RECEIVED, tx.TxId(), transfer)
+	}
+	return &receipt
+}
+
+// 中继提交给目标链的跨链消息, 包含发送方的区块头、区块的投票以及交易和回执在TxRoot和ReceiptRoot中的证明
+type CrossChainMessage struct {
+	SourceChainId string         `json:"sourceChainId"`
+	Header        Header         `json:"header"`
+	Votes         Votes          `json:"votes"`
+	Tx            types.HexBytes `json:"tx"`
+	TxProof       MPTPlus.Proof  `json:"txProof"`
+	Receipt       types.HexBytes `json:"receipt"`
+	ReceiptProof  MPTPlus.Proof  `json:"receiptProof"`
+}
+
+// 从区块中取出跨链交易和回执以及它们的证明
+func NewCrossChainMessage(sourceChainId string, header Header, votes Votes, txId []byte) (*CrossChainMessage, error) {
+	if header.TxRoot == nil || header.ReceiptRoot == nil {
+		return nil, errors.New("header has no txRoot or receiptRoot")
+	}
+	message := &CrossChainMessage{SourceChainId: sourceChainId, Header: header, Votes: votes}
+	var err error
+	if message.Tx, err = header.TxRoot.GetValue(txId); err != nil {
+		return nil, err
+	}
+	if message.TxProof, err = header.TxRoot.GetProof(txId); err != nil {
+		return nil, err
+	}
+	if message.Receipt, err = header.ReceiptRoot.GetValue(txId); err != nil {
+		return nil, err
+	}
+	if message.ReceiptProof, err = header.ReceiptRoot.GetProof(txId); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func crossChainReceivedKey(sourceChainId string, txId []byte) []byte {
+	return crypto.Sha3_256([]byte("crosschain:received:" + sourceChainId + ":" + hex.EncodeToString(txId)))
+}
+
+// 发送方公链的跨链交易是否已经在当前链上到账
+func (header Header) CrossChainReceived(sourceChainId string, txId []byte) bool {
+	return header.ChainStat != nil && header.ChainStat.ContainsKey(crossChainReceivedKey(sourceChainId, txId))
+}
+
+// 用注册时记录的外部公链委托人验证跨链消息, 返回消息中的跨链转账, TokenAddress为当前链上对应的Token
+func (header Header) VerifyCrossChainMessage(message CrossChainMessage) (*CrossChainTransfer, error) {
+	if header.ChainStat == nil {
+		return nil, ErrCrossChainNotEnabled
+	}
+	source := message.Header
+	if source.TxRoot == nil || source.ReceiptRoot == nil {
+		return nil, errors.New("header has no txRoot or receiptRoot")
+	}
+	var tx userevent.Transaction
+	if err := json.Unmarshal(message.Tx, &tx); err != nil {
+		return nil, err
+	}
+	op, ok := CrossChainSendOf(tx)
+	if !ok {
+		return nil, errors.New("not a crosschain transfer")
+	}
+	registration, err := header.GetCrossChainRegistration(message.SourceChainId, op.tokenId(tx.TokenAddress))
+	if err != nil || registration.Status != CROSSCHAIN_STATUS_ACTIVE {
+		return nil, errors.New("crosschain is not registered")
+	}
+	if op.PublicChainId != registration.localChainId() {
+		return nil, errors.New("not a crosschain transfer to this chain")
+	}
+	if !header.verifyCrossChainVotes(*registration, source, message.Votes) {
+		return nil, ErrInvalidCrossChainVote
+	}
+
+	txId := tx.TxId()
+	if !MPTPlus.VerifyProof(source.TxRoot.Root, txId, message.Tx, message.TxProof) ||
+		!MPTPlus.VerifyProof(source.ReceiptRoot.Root, txId, message.Receipt, message.ReceiptProof) {
+		return nil, errors.New("invalid proof")
+	}
+	var receipt userevent.TransactionReceipt
+	if err := json.Unmarshal(message.Receipt, &receipt); err != nil || !receipt.Success {
+		return nil, errors.New("crosschain transfer failed in source chain")
+	}
+	if header.CrossChainReceived(message.SourceChainId, txId) {
+		return nil, ErrCrossChainReceived
+	}
+	return &CrossChainTransfer{
+		TxId:          txId,
+		PublicChainId: message.SourceChainId,
+		From:          tx.From,
+		To:            op.To,
+		TokenAddress:  registration.TokenAddress,
+		Amount:        tx.Amount,
+	}, nil
+}
+
+// 区块头的投票必须来自外部公链注册的委托人, 并且超过当前链的投票阈值
+func (header Header) verifyCrossChainVotes(registration CrossChainRegistration, source Header, votes Votes) bool {
+	if len(registration.Delegates) == 0 || !votes.Validate() {
+		return false
+	}
+	hash := source.CalculateHash()
+	voters := make(map[string]bool)
+	for _, vote := range votes {
+		if !bytes.Equal(vote.Vote.BlockHash, hash) || vote.Vote.BlockHeight != source.Height ||
+			vote.Vote.BlockchainId != registration.blockchainId() || !registration.IsDelegate(vote.Peer.Account) {
+			return false
+		}
+		voters[strings.ToLower(vote.Peer.Account)] = true
+	}
+	return header.ChainParams().Majority(len(voters), len(registration.Delegates))
+}
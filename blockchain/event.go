@@ -0,0 +1,139 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+)
+
+const (
+	EVENT_DELEGATE_CHANGED  = "delegateChanged"
+	EVENT_TOKEN_ISSUED      = "tokenIssued"
+	EVENT_CONTRACT_DEPLOYED = "contractDeployed"
+	EVENT_CONTRACT_UPGRADED = "contractUpgraded"
+	EVENT_PARAM_CHANGED     = "paramChanged"
+)
+
+// 区块中发生的系统事件，按照发生的顺序保存在Header.ChainEvent中
+type ChainEvent struct {
+	Type   string          `json:"type"`
+	Height int64           `json:"height"`
+	Index  int64           `json:"index"`
+	TxId   types.HexBytes  `json:"txId,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type ContractEvent struct {
+	Address  types.HexBytes `json:"address"`
+	CodeHash types.HexBytes `json:"codeHash,omitempty"`
+	Native   string         `json:"native,omitempty"`
+}
+
+type TokenEvent struct {
+	Address types.HexBytes `json:"address"`
+	Symbol  string         `json:"symbol"`
+	Issuer  types.HexBytes `json:"issuer"`
+	Total   types.Amount   `json:"total"`
+}
+
+type DelegateEvent struct {
+	Delegates types.Peers `json:"delegates"`
+}
+
+// 事件在ChainEvent中的key, 使用事件序号的大端编码, 遍历时按照发生的顺序
+func EventKey(index int64) []byte {
+	key := make([]byte, 32)
+	binary.BigEndian.PutUint64(key[24:], uint64(index))
+	return key
+}
+
+// 记录一个系统事件, 没有ChainEvent的旧版本区块不记录
+func (header *Header) emitEvent(eventType string, txId []byte, data interface{}) {
+	if header.ChainEvent == nil {
+		return
+	}
+	value, err := json.Marshal(data)
+	if err != nil {
+		logErr(err)
+		return
+	}
+	event := ChainEvent{
+		Type:   eventType,
+		Height: header.Height,
+		Index:  header.eventCount,
+		TxId:   txId,
+		Data:   value,
+	}
+	body, _ := json.Marshal(event)
+	logErr(header.ChainEvent.MustInsert(EventKey(event.Index), body))
+	header.eventCount++
+}
+
+// 查询区块中的系统事件, eventType为空时返回全部事件
+func (header Header) GetEvents(eventType string) ([]ChainEvent, error) {
+	events := make([]ChainEvent, 0)
+	if header.ChainEvent == nil {
+		return events, nil
+	}
+	var err error
+	iterErr := header.ChainEvent.Iterate(func(key, value []byte) bool {
+		var event ChainEvent
+		if err = json.Unmarshal(value, &event); err != nil {
+			return false
+		}
+		if eventType == "" || event.Type == eventType {
+			events = append(events, event)
+		}
+		return true
+	})
+	if iterErr != nil {
+		return nil, iterErr
+	}
+	return events, err
+}
+
+// 查询结果中的合约事件, 附带所在的区块高度和交易id
+type ContractLogEntry struct {
+	userevent.ContractLog
+	Height int64          `json:"height"`
+	TxId   types.HexBytes `json:"txId"`
+}
+
+// 按照ReceiptRoot中交易id的顺序遍历本地保存的交易回执, fn返回false时停止
+func (header Header) IterateReceipts(fn func(receipt userevent.TransactionReceipt) bool) error {
+	if header.ReceiptRoot == nil {
+		return nil
+	}
+	var err error
+	iterErr := header.ReceiptRoot.Iterate(func(key, value []byte) bool {
+		var receipt userevent.TransactionReceipt
+		if err = json.Unmarshal(value, &receipt); err != nil {
+			return false
+		}
+		return fn(receipt)
+	})
+	if iterErr != nil {
+		return iterErr
+	}
+	return err
+}
+
+// 查询区块回执中合约记录的事件, address和name为空时不过滤, 按照ReceiptRoot中交易id的顺序返回
+func (header Header) GetLogs(address []byte, name string) ([]ContractLogEntry, error) {
+	logs := make([]ContractLogEntry, 0)
+	err := header.IterateReceipts(func(receipt userevent.TransactionReceipt) bool {
+		for _, log := range receipt.Logs {
+			if (len(address) == 0 || bytes.Equal(log.Address, address)) && (name == "" || log.Name == name) {
+				logs = append(logs, ContractLogEntry{ContractLog: log, Height: header.Height, TxId: receipt.TxId})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
@@ -0,0 +1,88 @@
+package blockchain
+
+import (
+	"github.com/EducationEKT/EKT/MPTPlus"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/db"
+	"github.com/EducationEKT/EKT/param"
+	"github.com/EducationEKT/EKT/vm"
+)
+
+// 激活gas计量之后合约执行的gas上限是交易的Fee
+func (block *Block) newVM(tx userevent.Transaction) *vm.Otto {
+	_vm := vm.NewVM(block.GetHeader(), db.GetDBInst())
+	if param.IsActive(param.FORK_VM_GAS, block.GetHeader().Height) {
+		_vm.SetGasLimit(tx.Fee)
+	}
+	return _vm
+}
+
+// 合约执行失败时的回执, gas不足和超时都会消耗全部的Fee
+// 激活gas计量之后超时的回执不会进入区块, 见vmTimedOut
+func vmFailReceipt(tx userevent.Transaction, err error, failType int) *userevent.TransactionReceipt {
+	switch err {
+	case vm.OUT_OF_GAS_ERROR:
+		failType = userevent.FailType_OUT_OF_GAS
+	case vm.TIMEOUT_ERROR:
+		failType = userevent.FailType_CONTRACT_TIMEOUT
+	}
+	receipt := userevent.NewTransactionReceipt(tx, false, failType)
+	return &receipt
+}
+
+// 打包交易时已经扣除了全部的Fee, 按照实际消耗收取手续费并退还没有使用的gas, 超时的交易不退还
+func (block *Block) chargeGas(tx userevent.Transaction, _vm *vm.Otto, receipt *userevent.TransactionReceipt) *userevent.TransactionReceipt {
+	if !param.IsActive(param.FORK_VM_GAS, block.GetHeader().Height) || receipt == nil || receipt.FailType == userevent.FailType_CONTRACT_TIMEOUT {
+		return receipt
+	}
+	used := _vm.GasUsed()
+	if refund := tx.Fee - used; refund > 0 {
+		account, err := block.GetHeader().GetAccount(tx.From)
+		if err != nil || account == nil {
+			account = types.NewAccount(tx.From)
+		}
+		account.Gas = account.Gas.Add(types.NewAmount(refund))
+		logErr(block.GetHeader().StatTree.MustInsert(tx.From, account.ToBytes()))
+	}
+	receipt.Fee = used
+	return receipt
+}
+
+// 激活gas计量之后合约的执行结果只由gas决定, 执行超时取决于节点自己的速度, 不能作为结果记录在回执中
+// 打包时撤销超时的交易并结束打包, 校验时超时作为本地的失败稍后重试, 不认为区块无效
+func (block *Block) vmTimedOut(receipt *userevent.TransactionReceipt) bool {
+	return receipt != nil && receipt.FailType == userevent.FailType_CONTRACT_TIMEOUT &&
+		param.IsActive(param.FORK_VM_GAS, block.GetHeader().Height)
+}
+
+// 区块状态的快照, 用于撤销打包时执行超时的交易
+type headerSnapshot struct {
+	statRoot       []byte
+	tokenRoot      []byte
+	chainStatRoot  []byte
+	chainEventRoot []byte
+	eventCount     int64
+}
+
+func (header *Header) snapshot() headerSnapshot {
+	return headerSnapshot{
+		statRoot:       header.StatTree.Root,
+		tokenRoot:      header.TokenTree.Root,
+		chainStatRoot:  header.chainStatRoot(),
+		chainEventRoot: header.chainEventRoot(),
+		eventCount:     header.eventCount,
+	}
+}
+
+func (header *Header) revert(snapshot headerSnapshot) {
+	header.StatTree = MPTPlus.MTP_Tree(db.GetDBInst(), snapshot.statRoot)
+	header.TokenTree = MPTPlus.MTP_Tree(db.GetDBInst(), snapshot.tokenRoot)
+	if header.ChainStat != nil {
+		header.ChainStat = MPTPlus.MTP_Tree(db.GetDBInst(), snapshot.chainStatRoot)
+	}
+	if header.ChainEvent != nil {
+		header.ChainEvent = MPTPlus.MTP_Tree(db.GetDBInst(), snapshot.chainEventRoot)
+	}
+	header.eventCount = snapshot.eventCount
+}
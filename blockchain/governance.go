@@ -0,0 +1,310 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/param"
+)
+
+const (
+	GOVERNANCE_OP_PROPOSE = "propose"
+	GOVERNANCE_OP_VOTE    = "vote"
+
+	PROPOSAL_STATUS_VOTING    = "voting"
+	PROPOSAL_STATUS_APPROVED  = "approved"
+	PROPOSAL_STATUS_ACTIVATED = "activated"
+	PROPOSAL_STATUS_REJECTED  = "rejected"
+
+	// 提案的生效高度至少在提交之后这么多个区块, 保证所有节点在生效之前都能看到提案
+	ProposalMinDelay = 20
+)
+
+const (
+	PARAM_BLOCK_INTERVAL  = "blockInterval"
+	PARAM_VM_CALL_TIMEOUT = "vmCallTimeout"
+	PARAM_VOTE_THRESHOLD  = "voteThreshold"
+	PARAM_DELEGATE_COUNT  = "delegateCount"
+	PARAM_MAX_TX_COUNT    = "maxTxCount"
+	PARAM_MAX_BODY_BYTES  = "maxBodyBytes"
+	PARAM_MAX_VM_TIME     = "maxVMTime"
+	PARAM_MAX_VM_TX_COUNT = "maxVMTxCount"
+)
+
+var (
+	chainParamsKey     = crypto.Sha3_256([]byte("governance:params"))
+	proposalListKey    = crypto.Sha3_256([]byte("governance:proposals"))
+	pendingProposalKey = crypto.Sha3_256([]byte("governance:pending"))
+
+	ErrUnknownParam = errors.New("unknown chain param")
+	ErrInvalidParam = errors.New("invalid chain param value")
+)
+
+// 可以通过链上治理修改的共识参数，保存在Header.ChainStat中，没有保存时使用默认值
+type ChainParams struct {
+	BlockInterval int64 `json:"blockInterval"` // 出块间隔，单位ms
+	VMCallTimeout int64 `json:"vmCallTimeout"` // 单个合约交易的最长执行时间，单位ms
+	VoteThreshold int64 `json:"voteThreshold"` // 区块和提案的投票数需要超过委托人数量的百分比
+	DelegateCount int64 `json:"delegateCount"` // 参与出块的委托人数量，0表示配置中的全部委托人
+	BlockLimits
+}
+
+var DefaultChainParams = ChainParams{
+	BlockInterval: int64(BackboneBlockInterval / time.Millisecond),
+	VMCallTimeout: int64(VM_CALL_TIMEOUT / time.Millisecond),
+	VoteThreshold: 50,
+	DelegateCount: 0,
+	BlockLimits:   DefaultBlockLimits,
+}
+
+func (params ChainParams) Interval() time.Duration {
+	return time.Duration(params.BlockInterval) * time.Millisecond
+}
+
+func (params ChainParams) VMTimeout() time.Duration {
+	return time.Duration(params.VMCallTimeout) * time.Millisecond
+}
+
+// 投票数是否超过了total的VoteThreshold%
+func (params ChainParams) Majority(votes, total int) bool {
+	return int64(votes)*100 > int64(total)*params.VoteThreshold
+}
+
+// 当前参与出块和治理的委托人
+func (params ChainParams) ActiveDelegates(peers types.Peers) types.Peers {
+	if params.DelegateCount > 0 && params.DelegateCount < int64(len(peers)) {
+		return peers[:params.DelegateCount]
+	}
+	return peers
+}
+
+func (params *ChainParams) Set(name string, value int64) error {
+	switch name {
+	case PARAM_BLOCK_INTERVAL:
+		params.BlockInterval = value
+	case PARAM_VM_CALL_TIMEOUT:
+		params.VMCallTimeout = value
+	case PARAM_VOTE_THRESHOLD:
+		params.VoteThreshold = value
+	case PARAM_DELEGATE_COUNT:
+		params.DelegateCount = value
+	case PARAM_MAX_TX_COUNT:
+		params.MaxTxCount = int(value)
+	case PARAM_MAX_BODY_BYTES:
+		params.MaxBodyBytes = int(value)
+	case PARAM_MAX_VM_TIME:
+		params.MaxVMTime = value
+	case PARAM_MAX_VM_TX_COUNT:
+		params.MaxVMTxCount = int(value)
+	default:
+		return ErrUnknownParam
+	}
+	if !params.Valid() {
+		return ErrInvalidParam
+	}
+	return nil
+}
+
+func (params ChainParams) Valid() bool {
+	return params.BlockInterval >= 500 &&
+		params.VMCallTimeout > 0 && params.VMCallTimeout <= params.MaxVMTime &&
+		params.MaxVMTime < params.BlockInterval &&
+		params.VoteThreshold >= 50 && params.VoteThreshold < 100 &&
+		params.DelegateCount >= 0 &&
+		params.MaxTxCount > 0 && params.MaxBodyBytes > 0 &&
+		params.MaxVMTxCount > 0 && params.MaxVMTxCount <= params.MaxTxCount
+}
+
+// 修改一个共识参数的提案，由委托人提交和投票，投票通过之后在ActivateHeight生效
+type Proposal struct {
+	Id             types.HexBytes   `json:"id"`
+	Proposer       types.HexBytes   `json:"proposer"`
+	Param          string           `json:"param"`
+	Value          int64            `json:"value"`
+	ActivateHeight int64            `json:"activateHeight"`
+	Votes          []types.HexBytes `json:"votes"`
+	Status         string           `json:"status"`
+}
+
+func (proposal Proposal) Bytes() []byte {
+	data, _ := json.Marshal(proposal)
+	return data
+}
+
+func (proposal Proposal) Voted(address []byte) bool {
+	for _, vote := range proposal.Votes {
+		if bytes.Equal(vote, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// 治理交易的参数, 提交提案时需要Param、Value和ActivateHeight, 投票时需要Proposal
+type GovernanceOp struct {
+	Op             string         `json:"op"`
+	Param          string         `json:"param,omitempty"`
+	Value          int64          `json:"value,omitempty"`
+	ActivateHeight int64          `json:"activateHeight,omitempty"`
+	Proposal       types.HexBytes `json:"proposal,omitempty"`
+}
+
+// 当前区块生效的共识参数
+func (header Header) ChainParams() ChainParams {
+	params := DefaultChainParams
+	if header.ChainStat != nil {
+		header.ChainStat.GetInterfaceValue(chainParamsKey, &params)
+	}
+	return params
+}
+
+// 区块所属的链当前生效的委托人
+func (header Header) Delegates() types.Peers {
+	return header.ChainParams().ActiveDelegates(param.ChainDelegates(header.ChainId()))
+}
+
+func (header Header) IsDelegate(address []byte) bool {
+	account := hex.EncodeToString(address)
+	for _, peer := range header.Delegates() {
+		if peer.Account == account {
+			return true
+		}
+	}
+	return false
+}
+
+func (header Header) GetProposal(id []byte) (*Proposal, error) {
+	if header.ChainStat == nil {
+		return nil, errors.New("governance is not enabled")
+	}
+	var proposal Proposal
+	if err := header.ChainStat.GetInterfaceValue(id, &proposal); err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// 按照提交顺序分页查询提案
+func (header Header) ListProposals(offset, limit int) ([]Proposal, error) {
+	proposals := make([]Proposal, 0)
+	ids := header.proposalIds(proposalListKey)
+	for i := offset; i < len(ids) && len(proposals) < limit; i++ {
+		proposal, err := header.GetProposal(ids[i])
+		if err != nil {
+			return nil, err
+		}
+		proposals = append(proposals, *proposal)
+	}
+	return proposals, nil
+}
+
+func (header Header) proposalIds(key []byte) []types.HexBytes {
+	ids := make([]types.HexBytes, 0)
+	if header.ChainStat != nil {
+		header.ChainStat.GetInterfaceValue(key, &ids)
+	}
+	return ids
+}
+
+func (header *Header) saveProposalIds(key []byte, ids []types.HexBytes) error {
+	data, _ := json.Marshal(ids)
+	return header.ChainStat.MustInsert(key, data)
+}
+
+func (header *Header) saveProposal(proposal Proposal) error {
+	return header.ChainStat.MustInsert(proposal.Id, proposal.Bytes())
+}
+
+// 新区块开始时让到达生效高度的提案生效，生效时参数不合法的提案被拒绝
+func (header *Header) activateProposals() {
+	pending := header.proposalIds(pendingProposalKey)
+	if len(pending) == 0 {
+		return
+	}
+	params := header.ChainParams()
+	left := make([]types.HexBytes, 0, len(pending))
+	for _, id := range pending {
+		proposal, err := header.GetProposal(id)
+		if err != nil {
+			continue
+		}
+		if proposal.ActivateHeight > header.Height {
+			left = append(left, id)
+			continue
+		}
+		next := params
+		if next.Set(proposal.Param, proposal.Value) == nil {
+			params = next
+			proposal.Status = PROPOSAL_STATUS_ACTIVATED
+			header.emitEvent(EVENT_PARAM_CHANGED, nil, proposal)
+			if proposal.Param == PARAM_DELEGATE_COUNT {
+				header.emitEvent(EVENT_DELEGATE_CHANGED, nil, DelegateEvent{params.ActiveDelegates(param.ChainDelegates(header.ChainId()))})
+			}
+		} else {
+			proposal.Status = PROPOSAL_STATUS_REJECTED
+		}
+		logErr(header.saveProposal(*proposal))
+	}
+	if len(left) == len(pending) {
+		return
+	}
+	data, _ := json.Marshal(params)
+	logErr(header.ChainStat.MustInsert(chainParamsKey, data))
+	logErr(header.saveProposalIds(pendingProposalKey, left))
+}
+
+// 治理系统合约, 只有当前的委托人可以提交提案和投票, 提交人自动投赞成票
+func (block *Block) Governance(tx userevent.Transaction) *userevent.TransactionReceipt {
+	var op GovernanceOp
+	header := block.GetHeader()
+	if err := json.Unmarshal([]byte(tx.Data), &op); err != nil || !tx.Amount.IsZero() {
+		return systemRefused(tx)
+	}
+	if header.ChainStat == nil || !header.IsDelegate(tx.From) {
+		return systemRefused(tx)
+	}
+
+	var proposal *Proposal
+	switch op.Op {
+	case GOVERNANCE_OP_PROPOSE:
+		params := header.ChainParams()
+		if op.ActivateHeight < header.Height+ProposalMinDelay || params.Set(op.Param, op.Value) != nil {
+			return systemRefused(tx)
+		}
+		proposal = &Proposal{
+			Id:             tx.TxId(),
+			Proposer:       tx.From,
+			Param:          op.Param,
+			Value:          op.Value,
+			ActivateHeight: op.ActivateHeight,
+			Votes:          []types.HexBytes{tx.From},
+			Status:         PROPOSAL_STATUS_VOTING,
+		}
+		logErr(header.saveProposalIds(proposalListKey, append(header.proposalIds(proposalListKey), proposal.Id)))
+	case GOVERNANCE_OP_VOTE:
+		var err error
+		proposal, err = header.GetProposal(op.Proposal)
+		if err != nil || proposal.Status != PROPOSAL_STATUS_VOTING ||
+			proposal.ActivateHeight <= header.Height || proposal.Voted(tx.From) {
+			return systemRefused(tx)
+		}
+		proposal.Votes = append(proposal.Votes, tx.From)
+	default:
+		return systemRefused(tx)
+	}
+
+	if header.ChainParams().Majority(len(proposal.Votes), len(header.Delegates())) {
+		proposal.Status = PROPOSAL_STATUS_APPROVED
+		logErr(header.saveProposalIds(pendingProposalKey, append(header.proposalIds(pendingProposalKey), proposal.Id)))
+	}
+	logErr(header.saveProposal(*proposal))
+
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	return &receipt
+}
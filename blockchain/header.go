@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/EducationEKT/EKT/MPTPlus"
 	"github.com/EducationEKT/EKT/core/types"
@@ -13,12 +14,16 @@ import (
 	"github.com/EducationEKT/EKT/crypto"
 	"github.com/EducationEKT/EKT/db"
 	"github.com/EducationEKT/EKT/log"
+	"github.com/EducationEKT/EKT/param"
 )
 
 const (
 	HEADER_VERSION_PURE_MTP = 0
 	HEADER_VERSION_MIXED    = 1
 	HEADER_VERSION_MERKLER  = 2
+	HEADER_VERSION_BIGINT   = 3 // 余额和金额支持超出int64范围
+	HEADER_VERSION_GOVERN   = 4 // 共识参数保存在ChainStat中，可以通过链上治理修改
+	HEADER_VERSION_EVENT    = 5 // 区块中的系统事件保存在ChainEvent中
 )
 
 type Header struct {
@@ -38,6 +43,18 @@ type Header struct {
 	ChainEvent *MPTPlus.MTP `json:"chainEvent"`
 
 	Version int `json:"version"`
+
+	eventCount int64
+	// 区块所属的链, 不参与区块hash, 由BlockChain设置并传递给之后的区块
+	chainId int64
+}
+
+// 区块所属的链, 没有设置时为主链
+func (header Header) ChainId() int64 {
+	if header.chainId == 0 {
+		return param.MainChainId
+	}
+	return header.chainId
 }
 
 func (header Header) Equal(peerHeader Header) bool {
@@ -47,7 +64,23 @@ func (header Header) Equal(peerHeader Header) bool {
 		bytes.Equal(header.PreviousHash, peerHeader.PreviousHash) &&
 		bytes.Equal(header.Coinbase, peerHeader.Coinbase) &&
 		bytes.Equal(header.TokenTree.Root, peerHeader.TokenTree.Root) &&
-		bytes.Equal(header.StatTree.Root, peerHeader.StatTree.Root)
+		bytes.Equal(header.StatTree.Root, peerHeader.StatTree.Root) &&
+		bytes.Equal(header.chainStatRoot(), peerHeader.chainStatRoot()) &&
+		bytes.Equal(header.chainEventRoot(), peerHeader.chainEventRoot())
+}
+
+func (header Header) chainStatRoot() []byte {
+	if header.ChainStat == nil {
+		return nil
+	}
+	return header.ChainStat.Root
+}
+
+func (header Header) chainEventRoot() []byte {
+	if header.ChainEvent == nil {
+		return nil
+	}
+	return header.ChainEvent.Root
 }
 
 func (header *Header) Bytes() []byte {
@@ -83,6 +116,10 @@ func (header Header) GetTimestamp() int64 {
 	return header.Timestamp
 }
 
+func (header Header) GetHeight() int64 {
+	return header.Height
+}
+
 func (header Header) Author() []byte {
 	return header.Coinbase
 }
@@ -113,19 +150,19 @@ func GenesisHeader(accounts []types.Account) *Header {
 	return header
 }
 
-func NewHeader(last Header, packTime int64, parentHash types.HexBytes, coinbase types.HexBytes) *Header {
-	header := &Header{
-		Height:       last.Height + 1,
-		Timestamp:    packTime,
-		TotalFee:     0,
-		PreviousHash: parentHash,
-		Coinbase:     coinbase,
-		StatTree:     MPTPlus.MTP_Tree(db.GetDBInst(), last.StatTree.Root),
-		TokenTree:    MPTPlus.MTP_Tree(db.GetDBInst(), last.TokenTree.Root),
-		Version:      HEADER_VERSION_MIXED,
+// 根据升级计划决定高度为height的区块头版本
+func HeaderVersion(height int64) int {
+	switch {
+	case param.IsActive(param.FORK_CHAIN_EVENT, height):
+		return HEADER_VERSION_EVENT
+	case param.IsActive(param.FORK_GOVERN, height):
+		return HEADER_VERSION_GOVERN
+	case param.IsActive(param.FORK_BIGINT, height):
+		return HEADER_VERSION_BIGINT
+	case param.IsActive(param.FORK_MERKLER, height):
+		return HEADER_VERSION_MERKLER
 	}
-
-	return header
+	return HEADER_VERSION_MIXED
 }
 
 func NewHeader_V2(last Header, packTime int64, parentHash types.HexBytes, coinbase types.HexBytes) *Header {
@@ -139,7 +176,19 @@ func NewHeader_V2(last Header, packTime int64, parentHash types.HexBytes, coinba
 		TokenTree:    MPTPlus.MTP_Tree(db.GetDBInst(), last.TokenTree.Root),
 		TxRoot:       MPTPlus.NewMTP(db.GetDBInst()),
 		ReceiptRoot:  MPTPlus.NewMTP(db.GetDBInst()),
-		Version:      HEADER_VERSION_MERKLER,
+		Version:      HeaderVersion(last.Height + 1),
+		chainId:      last.chainId,
+	}
+	if header.Version >= HEADER_VERSION_EVENT {
+		header.ChainEvent = MPTPlus.NewMTP(db.GetDBInst())
+	}
+	if header.Version >= HEADER_VERSION_GOVERN {
+		header.ChainStat = MPTPlus.MTP_Tree(db.GetDBInst(), last.chainStatRoot())
+		header.activateProposals()
+	}
+	// 创世块保持不变, Bancor合约在升级激活的区块安装
+	if param.ActivatesAt(param.FORK_BANCOR, header.Height) {
+		header.installSystemBancor()
 	}
 
 	return header
@@ -148,6 +197,12 @@ func NewHeader_V2(last Header, packTime int64, parentHash types.HexBytes, coinba
 func (header *Header) NewSubTransaction(txs userevent.SubTransactions) bool {
 	changes := make(map[string]*types.AccountChange)
 
+	for _, tx := range txs {
+		if header.IsFrozen(tx.TokenAddress, tx.From) || header.IsFrozen(tx.TokenAddress, tx.To) {
+			return false
+		}
+	}
+
 	for _, tx := range txs {
 		from, exist := changes[hex.EncodeToString(tx.From)]
 		if !exist {
@@ -164,23 +219,47 @@ func (header *Header) NewSubTransaction(txs userevent.SubTransactions) bool {
 		changes[hex.EncodeToString(tx.To)] = to
 	}
 
-	for addr, change := range changes {
+	addresses := make([]string, 0, len(changes))
+	for addr := range changes {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+
+	// 先按照地址的顺序在内存中修改和检查所有账户, 全部通过之后再写入状态树, 失败时状态保持不变
+	accounts := make(map[string]*types.Account)
+	order := make([]string, 0)
+	loadAccount := func(address []byte) (*types.Account, bool) {
+		key := hex.EncodeToString(address)
+		if account, exist := accounts[key]; exist {
+			return account, true
+		}
+		account, err := header.GetAccount(address)
+		if err != nil || account == nil {
+			return nil, false
+		}
+		accounts[key] = account
+		order = append(order, key)
+		return account, true
+	}
+	for _, addr := range addresses {
+		change := changes[addr]
 		address, err := hex.DecodeString(addr)
 		if err != nil {
 			return false
 		}
 		if len(address) == types.AccountAddressLength {
-			account, err := header.GetAccount(address)
-			if err != nil || account == nil {
+			account, exist := loadAccount(address)
+			if !exist {
 				account = types.NewAccount(address)
+				accounts[addr] = account
+				order = append(order, addr)
 			}
 			if !account.Transfer(*change) {
 				return false
 			}
-			header.StatTree.MustInsert(account.Address, account.ToBytes())
 		} else if len(address) == types.ContractAddressLength {
-			account, err := header.GetAccount(address[:32])
-			if err != nil || account == nil || account.Contracts == nil {
+			account, exist := loadAccount(address[:32])
+			if !exist || account.Contracts == nil {
 				return false
 			}
 			contractAccount, exist := account.Contracts[hex.EncodeToString(address[32:])]
@@ -191,11 +270,18 @@ func (header *Header) NewSubTransaction(txs userevent.SubTransactions) bool {
 				return false
 			}
 			account.Contracts[hex.EncodeToString(address[32:])] = contractAccount
-			header.StatTree.MustInsert(account.Address, account.ToBytes())
 		} else {
 			return false
 		}
 	}
+	for _, key := range order {
+		if !header.allowAccount(*accounts[key]) {
+			return false
+		}
+	}
+	for _, key := range order {
+		header.StatTree.MustInsert(accounts[key].Address, accounts[key].ToBytes())
+	}
 	return true
 }
 
@@ -217,6 +303,48 @@ func (header *Header) ModifyContract(address, data []byte) error {
 	return header.StatTree.MustInsert(account.Address, account.ToBytes())
 }
 
+// 合约执行成功之后更新合约存储的根节点
+func (header *Header) ModifyContractStorage(address, root []byte) error {
+	if len(address) != types.ContractAddressLength {
+		return errors.New("invalid contract address")
+	}
+	accountAddr, contractAddr := address[:32], address[32:]
+	account, err := header.GetAccount(accountAddr)
+	if err != nil || account == nil || len(account.Contracts) == 0 {
+		return errors.New("invalid contract address")
+	}
+	contractAccount, exist := account.Contracts[hex.EncodeToString(contractAddr)]
+	if !exist {
+		return errors.New("invalid contract address")
+	}
+	contractAccount.StorageRoot = root
+	account.Contracts[hex.EncodeToString(contractAddr)] = contractAccount
+	return header.StatTree.MustInsert(account.Address, account.ToBytes())
+}
+
+// 修改合约的数据, 子交易执行之后重新读取账户, 不覆盖子交易对余额的修改
+func (header *Header) ModifyContractData(address []byte, data string) error {
+	if len(address) != types.ContractAddressLength {
+		return errors.New("invalid contract address")
+	}
+	accountAddr, contractAddr := address[:32], address[32:]
+	account, err := header.GetAccount(accountAddr)
+	if err != nil || account == nil || len(account.Contracts) == 0 {
+		return errors.New("invalid contract address")
+	}
+	contractAccount, exist := account.Contracts[hex.EncodeToString(contractAddr)]
+	if !exist {
+		return errors.New("invalid contract address")
+	}
+	contractAccount.ContractData.Contract = data
+	account.Contracts[hex.EncodeToString(contractAddr)] = contractAccount
+	return header.StatTree.MustInsert(account.Address, account.ToBytes())
+}
+
+func (header *Header) allowAccount(account types.Account) bool {
+	return header.Version >= HEADER_VERSION_BIGINT || account.FitsInt64()
+}
+
 func (header *Header) HandleTx(from, to *types.Account, tx userevent.SubTransaction) bool {
 	if !header.CheckSubTx(from, to, tx) {
 		return false
@@ -226,57 +354,21 @@ func (header *Header) HandleTx(from, to *types.Account, tx userevent.SubTransact
 }
 
 func (header *Header) Transfer(from, to *types.Account, tx userevent.SubTransaction) bool {
-	if len(tx.From) == 32 {
-		switch tx.TokenAddress {
-		case types.EKTAddress:
-			from.Amount -= tx.Amount
-		case types.GasAddress:
-			from.Gas -= tx.Amount
-		default:
-			from.Balances[tx.TokenAddress] -= tx.Amount
-		}
-	} else {
-		contractAccount := from.Contracts[hex.EncodeToString(tx.From[32:])]
-		switch tx.TokenAddress {
-		case types.EKTAddress:
-			contractAccount.Amount -= tx.Amount
-		case types.GasAddress:
-			contractAccount.Gas -= tx.Amount
-		default:
-			contractAccount.Balances[tx.TokenAddress] -= tx.Amount
-		}
-		from.Contracts[hex.EncodeToString(tx.From[32:])] = contractAccount
-	}
-
-	if len(tx.To) == 32 {
-		switch tx.TokenAddress {
-		case types.EKTAddress:
-			to.Amount += tx.Amount
-		case types.GasAddress:
-			to.Gas += tx.Amount
-		default:
-			if to.Balances == nil {
-				to.Balances = map[string]int64{}
-				to.Balances[tx.TokenAddress] = 0
-			}
-			to.Balances[tx.TokenAddress] += tx.Amount
-		}
-	} else {
-		contractAccount := to.Contracts[hex.EncodeToString(tx.To[32:])]
-		switch tx.TokenAddress {
-		case types.EKTAddress:
-			contractAccount.Amount += tx.Amount
-		case types.GasAddress:
-			contractAccount.Gas += tx.Amount
-		default:
-			if contractAccount.Balances == nil {
-				contractAccount.Balances = map[string]int64{}
-				contractAccount.Balances[tx.TokenAddress] = 0
-			}
-			contractAccount.Balances[tx.TokenAddress] += tx.Amount
-		}
-		to.Contracts[hex.EncodeToString(tx.To[32:])] = contractAccount
+	reduce, add := types.NewAccountChange(), types.NewAccountChange()
+	reduce.Reduce(tx.TokenAddress, tx.Amount)
+	add.Add(tx.TokenAddress, tx.Amount)
+	return transferAccount(from, tx.From, *reduce) && transferAccount(to, tx.To, *add)
+}
+
+func transferAccount(account *types.Account, address []byte, change types.AccountChange) bool {
+	if len(address) == types.AccountAddressLength {
+		return account.Transfer(change)
 	}
+	contractAccount := account.Contracts[hex.EncodeToString(address[32:])]
+	if !contractAccount.Transfer(change) {
+		return false
+	}
+	account.Contracts[hex.EncodeToString(address[32:])] = contractAccount
 	return true
 }
 
@@ -285,33 +377,30 @@ func (header *Header) CheckSubTx(from, to *types.Account, tx userevent.SubTransa
 		return false
 	}
 	if len(tx.From) == 32 {
-		switch tx.TokenAddress {
-		case types.EKTAddress:
-			return from.Amount >= tx.Amount
-		case types.GasAddress:
-			return from.Gas >= tx.Amount
-		default:
-			if from.Balances != nil && from.Balances[tx.TokenAddress] >= tx.Amount {
-				return true
-			}
-		}
+		return balanceOf(from.Amount, from.Gas, from.Balances, tx.TokenAddress).Cmp(tx.Amount) >= 0
 	} else if from.Contracts == nil {
 		return false
 	} else {
 		subAddr := tx.From[32:]
 		contractAccount := from.Contracts[hex.EncodeToString(subAddr)]
-		switch tx.TokenAddress {
-		case types.EKTAddress:
-			return contractAccount.Amount >= tx.Amount
-		case types.GasAddress:
-			return contractAccount.Gas >= tx.Amount
-		default:
-			if contractAccount.Balances != nil && contractAccount.Balances[tx.TokenAddress] >= tx.Amount {
-				return true
-			}
-		}
+		return balanceOf(contractAccount.Amount, contractAccount.Gas, contractAccount.Balances, tx.TokenAddress).Cmp(tx.Amount) >= 0
 	}
-	return false
+}
+
+func balanceOf(amount, gas types.Amount, balances map[string]types.Amount, tokenAddress string) types.Amount {
+	switch tokenAddress {
+	case types.EKTAddress:
+		return amount
+	case types.GasAddress:
+		return gas
+	default:
+		return balances[tokenAddress]
+	}
+}
+
+// 旧版本的区块只允许int64范围之内的金额
+func (header *Header) AllowAmount(amount types.Amount) bool {
+	return header.Version >= HEADER_VERSION_BIGINT || amount.IsInt64()
 }
 
 func (header *Header) CheckFromAndBurnGas(tx userevent.Transaction) bool {
@@ -319,32 +408,41 @@ func (header *Header) CheckFromAndBurnGas(tx userevent.Transaction) bool {
 		return false
 	}
 
-	if len(tx.To) != 0 && len(tx.To) != types.AccountAddressLength && len(tx.To) != types.ContractAddressLength && len(tx.To) != types.ContractAddressLength+1 {
+	if len(tx.To) != 0 && len(tx.To) != types.AccountAddressLength && len(tx.To) != types.ContractAddressLength &&
+		len(tx.To) != types.ContractAddressLength+1 && len(tx.To) != types.ExternalAddressLength {
+		return false
+	}
+	// 外部地址的转账需要跨链注册, 跨链升级之前和没有ChainStat的旧版本区块不接受
+	if len(tx.To) == types.ExternalAddressLength && (header.ChainStat == nil || !param.IsActive(param.FORK_CROSSCHAIN, header.Height) || !types.IsExternalAddress(tx.To)) {
+		return false
+	}
+	if tx.Amount.Sign() < 0 || !header.AllowAmount(tx.Amount) {
 		return false
 	}
-	if tx.Amount < 0 {
+	if !tx.Supported(header.Height) || !tx.ValidAt(header.Height, header.Timestamp) {
+		return false
+	}
+	// Token升级之前转账的Token不需要在TokenTree中, 保持历史区块的回执不变
+	if param.IsActive(param.FORK_TOKEN, header.Height) && !header.ExistToken(tx.TokenAddress) {
 		return false
 	}
 	account, err := header.GetAccount(tx.GetFrom())
 	if err != nil || account == nil {
 		account = types.NewAccount(tx.From)
 	}
-	if account.Gas < tx.Fee || account.GetNonce()+1 != tx.GetNonce() {
+	if !userevent.CheckAuthorization(tx, account) {
 		return false
 	}
-	switch tx.TokenAddress {
-	case types.EKTAddress:
-		if account.Amount < tx.Amount {
-			return false
-		}
-	case types.GasAddress:
-		if account.Gas < tx.Amount+tx.Fee {
-			return false
-		}
-	default:
-		if account.Balances == nil || account.Balances[tx.TokenAddress] < tx.Amount {
-			return false
-		}
+	fee := types.NewAmount(tx.Fee)
+	if tx.Fee < 0 || account.Gas.Cmp(fee) < 0 || account.GetNonce()+1 != tx.GetNonce() {
+		return false
+	}
+	required := tx.Amount
+	if tx.TokenAddress == types.GasAddress {
+		required = required.Add(fee)
+	}
+	if balanceOf(account.Amount, account.Gas, account.Balances, tx.TokenAddress).Cmp(required) < 0 {
+		return false
 	}
 	account.BurnGas(tx.Fee)
 	header.StatTree.MustInsert(account.Address, account.ToBytes())
@@ -369,7 +467,7 @@ func (header *Header) UpdateMiner() {
 	if account == nil || err != nil {
 		account = types.NewAccount(header.Coinbase)
 	}
-	account.Gas += header.TotalFee
+	account.Gas = account.Gas.Add(types.NewAmount(header.TotalFee))
 	err = header.StatTree.MustInsert(header.Coinbase, account.ToBytes())
 	if err != nil {
 		log.Crit("Update miner failed, %s", err.Error())
@@ -0,0 +1,55 @@
+package blockchain
+
+import (
+	"time"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+)
+
+// 区块大小的共识参数，打包和校验区块时都需要遵守
+type BlockLimits struct {
+	MaxTxCount   int   `json:"maxTxCount"`   // 区块中最多包含的交易数量
+	MaxBodyBytes int   `json:"maxBodyBytes"` // 区块中所有交易序列化之后的最大字节数
+	MaxVMTime    int64 `json:"maxVMTime"`    // 打包时合约交易累计的最大执行时间，单位ms，只用于本地打包
+	MaxVMTxCount int   `json:"maxVMTxCount"` // 区块中最多包含的合约交易数量，包括部署、升级和调用
+}
+
+var DefaultBlockLimits = BlockLimits{
+	MaxTxCount:   10000,
+	MaxBodyBytes: 4 * 1024 * 1024,
+	MaxVMTime:    1000,
+	MaxVMTxCount: 1000,
+}
+
+func (limits BlockLimits) VMTimeLimit() time.Duration {
+	return time.Duration(limits.MaxVMTime) * time.Millisecond
+}
+
+// 校验区块中的交易数量、大小和合约交易数量是否超出限制
+func (limits BlockLimits) CheckBody(txs []userevent.Transaction) bool {
+	if len(txs) > limits.MaxTxCount || CountVMTransactions(txs) > limits.MaxVMTxCount {
+		return false
+	}
+	size := 0
+	for _, tx := range txs {
+		size += len(tx.Bytes())
+	}
+	return size <= limits.MaxBodyBytes
+}
+
+// 交易中需要虚拟机执行的交易数量
+func CountVMTransactions(txs []userevent.Transaction) int {
+	count := 0
+	for _, tx := range txs {
+		if IsVMTransaction(tx) {
+			count++
+		}
+	}
+	return count
+}
+
+// 需要虚拟机执行的交易：部署合约、升级合约和调用合约, 系统合约由链直接执行
+func IsVMTransaction(tx userevent.Transaction) bool {
+	return len(tx.To) != types.AccountAddressLength && !IsSystemContract(tx.To)
+}
@@ -0,0 +1,135 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/EducationEKT/EKT/contract"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/param"
+)
+
+// 系统合约的地址以SYSTEM_AUTHOR开头，由链直接执行，不经过vm
+func IsSystemContract(address []byte) bool {
+	return len(address) == types.ContractAddressLength && hex.EncodeToString(address[:32]) == contract.SYSTEM_AUTHOR
+}
+
+// 系统合约和启用它的升级
+var systemContractForks = map[string]string{
+	contract.EKT_GAS_BANCOR_CONTRACT: param.FORK_BANCOR,
+	contract.MULTISIG_CONTRACT:       param.FORK_TX_EXTENSION,
+	contract.KEY_ROTATION_CONTRACT:   param.FORK_KEY_ROTATION,
+	contract.TOKEN_ISSUE_CONTRACT:    param.FORK_TOKEN,
+	contract.TOKEN_ADMIN_CONTRACT:    param.FORK_TOKEN,
+	contract.BANCOR_FACTORY_CONTRACT: param.FORK_CONVERTER,
+	contract.GOVERNANCE_CONTRACT:     param.FORK_GOVERN,
+	contract.CROSSCHAIN_CONTRACT:     param.FORK_CROSSCHAIN,
+}
+
+// 系统合约在高度height是否已经启用
+func SystemContractActive(address []byte, height int64) bool {
+	if !IsSystemContract(address) {
+		return false
+	}
+	fork, exist := systemContractForks[hex.EncodeToString(address[32:])]
+	return exist && param.IsActive(fork, height)
+}
+
+func (block *Block) SystemCall(tx userevent.Transaction) *userevent.TransactionReceipt {
+	// 启用之前系统合约地址上没有合约, 和旧版本一样按照找不到合约处理, 重放历史区块时回执保持不变
+	if !SystemContractActive(tx.To, block.GetHeader().Height) {
+		receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_INVALID_ADDRESS)
+		return &receipt
+	}
+	switch hex.EncodeToString(tx.To[32:]) {
+	case contract.EKT_GAS_BANCOR_CONTRACT:
+		return block.BancorCall(tx)
+	case contract.MULTISIG_CONTRACT:
+		return block.CreateMultiSig(tx)
+	case contract.KEY_ROTATION_CONTRACT:
+		return block.RotateKey(tx)
+	case contract.TOKEN_ISSUE_CONTRACT:
+		return block.IssueToken(tx)
+	case contract.TOKEN_ADMIN_CONTRACT:
+		return block.AdminToken(tx)
+	case contract.BANCOR_FACTORY_CONTRACT:
+		return block.DeployConverter(tx)
+	case contract.GOVERNANCE_CONTRACT:
+		return block.Governance(tx)
+	case contract.CROSSCHAIN_CONTRACT:
+		return block.CrossChain(tx)
+	}
+	receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_INVALID_CONTRACT_ADDRESS)
+	return &receipt
+}
+
+// 创建多签账户, tx.Data为MultiSig的json, 交易的Amount作为多签账户的初始资金
+func (block *Block) CreateMultiSig(tx userevent.Transaction) *userevent.TransactionReceipt {
+	var param types.MultiSig
+	if err := json.Unmarshal([]byte(tx.Data), &param); err != nil {
+		return systemRefused(tx)
+	}
+	members := make([][]byte, 0, len(param.Members))
+	for _, member := range param.Members {
+		members = append(members, member)
+	}
+	multiSig := types.NewMultiSig(members, param.Threshold)
+	if !multiSig.Valid() {
+		return systemRefused(tx)
+	}
+
+	address := multiSig.Address()
+	if account, err := block.GetHeader().GetAccount(address); err == nil && account != nil && account.MultiSig != nil {
+		return systemRefused(tx)
+	}
+
+	// 先转入初始资金, 转账失败时不创建多签账户
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	if tx.Amount.Sign() > 0 {
+		subTx := userevent.NewSubTransaction(tx.TxId(), tx.From, address, tx.Amount, "", tx.TokenAddress)
+		txs := userevent.SubTransactions{*subTx}
+		receipt.SubTransactions = txs
+		if receipt.Success = block.GetHeader().NewSubTransaction(txs); !receipt.Success {
+			return &receipt
+		}
+	}
+	account, err := block.GetHeader().GetAccount(address)
+	if err != nil || account == nil {
+		account = types.NewAccount(address)
+	}
+	account.MultiSig = multiSig
+	logErr(block.GetHeader().StatTree.MustInsert(address, account.ToBytes()))
+	return &receipt
+}
+
+type KeyRotation struct {
+	AuthKey types.HexBytes `json:"authKey"`
+}
+
+// 轮换发送方账户的公钥, 之后的交易需要使用新公钥对应的私钥签名, 账户地址保持不变
+// AuthKey为新公钥的hash(FromPubKeyToAddress), 等于账户地址时恢复使用地址对应的公钥
+func (block *Block) RotateKey(tx userevent.Transaction) *userevent.TransactionReceipt {
+	var param KeyRotation
+	if err := json.Unmarshal([]byte(tx.Data), &param); err != nil || len(param.AuthKey) != types.AccountAddressLength || !tx.Amount.IsZero() {
+		return systemRefused(tx)
+	}
+	account, err := block.GetHeader().GetAccount(tx.From)
+	if err != nil || account == nil || account.MultiSig != nil {
+		return systemRefused(tx)
+	}
+	if bytes.Equal(param.AuthKey, account.Address) {
+		account.AuthKey = nil
+	} else {
+		account.AuthKey = param.AuthKey
+	}
+	logErr(block.GetHeader().StatTree.MustInsert(account.Address, account.ToBytes()))
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	return &receipt
+}
+
+func systemRefused(tx userevent.Transaction) *userevent.TransactionReceipt {
+	receipt := userevent.NewTransactionReceipt(tx, false, userevent.FailType_CONTRACT_ERROR)
+	return &receipt
+}
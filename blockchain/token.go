@@ -0,0 +1,293 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+)
+
+func (header Header) GetToken(address []byte) (*types.Token, error) {
+	var token types.Token
+	err := header.TokenTree.GetInterfaceValue(address, &token)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// 根据Symbol查找Token的地址
+func (header Header) GetTokenAddress(symbol string) ([]byte, error) {
+	return header.TokenTree.GetValue(types.TokenSymbolKey(symbol))
+}
+
+// 交易中的TokenAddress必须是EKT、Gas或者已经发行的Token
+func (header Header) ExistToken(tokenAddress string) bool {
+	if tokenAddress == types.EKTAddress || tokenAddress == types.GasAddress {
+		return true
+	}
+	address, err := hex.DecodeString(tokenAddress)
+	if err != nil || len(address) != types.AccountAddressLength {
+		return false
+	}
+	// Symbol索引和冻结记录的key长度和Token地址相同, 需要确认是Token本身的记录
+	value, err := header.TokenTree.GetValue(address)
+	if err != nil {
+		return false
+	}
+	_, exist := header.isTokenEntry(address, value)
+	return exist
+}
+
+// 发行Token, tx.Data为Token的json, 发行的全部Token转入发行人的账户
+func (block *Block) IssueToken(tx userevent.Transaction) *userevent.TransactionReceipt {
+	var token types.Token
+	if err := json.Unmarshal([]byte(tx.Data), &token); err != nil || !tx.Amount.IsZero() {
+		return systemRefused(tx)
+	}
+	token.Issuer = tx.From
+	if !token.Valid() {
+		return systemRefused(tx)
+	}
+
+	header := block.GetHeader()
+	address := token.Address()
+	symbolKey := types.TokenSymbolKey(token.Symbol)
+	if header.TokenTree.ContainsKey(address) || header.TokenTree.ContainsKey(symbolKey) {
+		return systemRefused(tx)
+	}
+	account, err := header.GetAccount(tx.From)
+	if err != nil || account == nil {
+		return systemRefused(tx)
+	}
+	change := types.NewAccountChange()
+	change.Add(hex.EncodeToString(address), token.Total)
+	if !account.Transfer(*change) || !header.allowAccount(*account) {
+		return systemRefused(tx)
+	}
+
+	logErr(header.TokenTree.MustInsert(address, token.Bytes()))
+	logErr(header.TokenTree.MustInsert(symbolKey, address))
+	logErr(header.StatTree.MustInsert(account.Address, account.ToBytes()))
+	header.emitEvent(EVENT_TOKEN_ISSUED, tx.TxId(), TokenEvent{
+		Address: address,
+		Symbol:  token.Symbol,
+		Issuer:  token.Issuer,
+		Total:   token.Total,
+	})
+
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	return &receipt
+}
+
+const (
+	TOKEN_OP_MINT     = "mint"
+	TOKEN_OP_BURN     = "burn"
+	TOKEN_OP_FREEZE   = "freeze"
+	TOKEN_OP_UNFREEZE = "unfreeze"
+	TOKEN_OP_METADATA = "metadata"
+)
+
+// Token的管理操作, 除了burn之外都只能由发行人发起
+type TokenAdmin struct {
+	Op       string              `json:"op"`
+	Token    string              `json:"token"`
+	Amount   types.Amount        `json:"amount"`
+	Holder   types.HexBytes      `json:"holder,omitempty"`
+	Name     string              `json:"name,omitempty"`
+	Metadata types.TokenMetadata `json:"metadata,omitempty"`
+}
+
+// 持有人的Token余额是否被冻结，被冻结的余额不能转入也不能转出
+func (header Header) IsFrozen(tokenAddress string, holder []byte) bool {
+	if tokenAddress == types.EKTAddress || tokenAddress == types.GasAddress {
+		return false
+	}
+	var frozen bool
+	err := header.TokenTree.GetInterfaceValue(types.TokenFrozenKey(tokenAddress, holder), &frozen)
+	return err == nil && frozen
+}
+
+func (block *Block) AdminToken(tx userevent.Transaction) *userevent.TransactionReceipt {
+	var param TokenAdmin
+	if err := json.Unmarshal([]byte(tx.Data), &param); err != nil || !tx.Amount.IsZero() {
+		return systemRefused(tx)
+	}
+	header := block.GetHeader()
+	address, err := hex.DecodeString(param.Token)
+	if err != nil || len(address) != types.AccountAddressLength {
+		return systemRefused(tx)
+	}
+	token, err := header.GetToken(address)
+	if err != nil || token == nil {
+		return systemRefused(tx)
+	}
+	if param.Op != TOKEN_OP_BURN && !token.IsIssuer(tx.From) {
+		return systemRefused(tx)
+	}
+
+	var ok bool
+	switch param.Op {
+	case TOKEN_OP_MINT:
+		ok = header.mintToken(tx.From, param.Token, token, param.Amount)
+	case TOKEN_OP_BURN:
+		ok = header.burnToken(tx.From, param.Token, token, param.Amount)
+	case TOKEN_OP_FREEZE, TOKEN_OP_UNFREEZE:
+		ok = header.freezeToken(param.Token, token, param.Holder, param.Op == TOKEN_OP_FREEZE)
+	case TOKEN_OP_METADATA:
+		if param.Name != "" {
+			token.Name = param.Name
+		}
+		token.TokenMetadata = param.Metadata
+		ok = true
+	}
+	if !ok {
+		return systemRefused(tx)
+	}
+	logErr(header.TokenTree.MustInsert(address, token.Bytes()))
+
+	receipt := userevent.NewTransactionReceipt(tx, true, userevent.FailType_SUCCESS)
+	return &receipt
+}
+
+// 增发的Token转入发行人的账户, 增发之后的总量不能超过Cap
+func (header *Header) mintToken(issuer []byte, tokenAddress string, token *types.Token, amount types.Amount) bool {
+	if amount.Sign() <= 0 || token.Cap == nil || token.Total.Add(amount).Cmp(*token.Cap) > 0 || header.IsFrozen(tokenAddress, issuer) {
+		return false
+	}
+	account, err := header.GetAccount(issuer)
+	if err != nil || account == nil {
+		return false
+	}
+	change := types.NewAccountChange()
+	change.Add(tokenAddress, amount)
+	if !account.Transfer(*change) || !header.allowAccount(*account) {
+		return false
+	}
+	token.Total = token.Total.Add(amount)
+	return header.StatTree.MustInsert(account.Address, account.ToBytes()) == nil
+}
+
+// 持有人销毁自己账户中的Token
+func (header *Header) burnToken(holder []byte, tokenAddress string, token *types.Token, amount types.Amount) bool {
+	if amount.Sign() <= 0 || !token.Burnable || header.IsFrozen(tokenAddress, holder) {
+		return false
+	}
+	account, err := header.GetAccount(holder)
+	if err != nil || account == nil {
+		return false
+	}
+	change := types.NewAccountChange()
+	change.Reduce(tokenAddress, amount)
+	if !account.Transfer(*change) {
+		return false
+	}
+	token.Total = token.Total.Sub(amount)
+	return header.StatTree.MustInsert(account.Address, account.ToBytes()) == nil
+}
+
+func (header *Header) freezeToken(tokenAddress string, token *types.Token, holder []byte, frozen bool) bool {
+	if !token.Freezable || (len(holder) != types.AccountAddressLength && len(holder) != types.ContractAddressLength) {
+		return false
+	}
+	value, _ := json.Marshal(frozen)
+	return header.TokenTree.MustInsert(types.TokenFrozenKey(tokenAddress, holder), value) == nil
+}
+
+type TokenInfo struct {
+	Address types.HexBytes `json:"address"`
+	types.Token
+	Circulating types.Amount `json:"circulating"` // 总量减去发行人持有的数量
+}
+
+type TokenHolder struct {
+	Address types.HexBytes `json:"address"`
+	Amount  types.Amount   `json:"amount"`
+	Frozen  bool           `json:"frozen"`
+}
+
+func (header Header) GetTokenInfo(address []byte) (*TokenInfo, error) {
+	token, err := header.GetToken(address)
+	if err != nil {
+		return nil, err
+	}
+	info := &TokenInfo{Address: address, Token: *token, Circulating: token.Total}
+	if issuer, err := header.GetAccount(token.Issuer); err == nil && issuer != nil {
+		info.Circulating = info.Circulating.Sub(issuer.Balances[hex.EncodeToString(address)])
+	}
+	return info, nil
+}
+
+// TokenTree中同时保存了Symbol索引和冻结记录, 只有Symbol索引指向自己的记录才是Token
+func (header Header) isTokenEntry(key, value []byte) (*types.Token, bool) {
+	var token types.Token
+	if err := json.Unmarshal(value, &token); err != nil || token.Symbol == "" {
+		return nil, false
+	}
+	address, err := header.GetTokenAddress(token.Symbol)
+	if err != nil || !bytes.Equal(address, key) {
+		return nil, false
+	}
+	return &token, true
+}
+
+// 按照地址的顺序分页返回已经发行的Token
+func (header Header) ListTokens(offset, limit int) ([]TokenInfo, error) {
+	tokens := make([]TokenInfo, 0)
+	index := 0
+	err := header.TokenTree.Iterate(func(key, value []byte) bool {
+		if _, ok := header.isTokenEntry(key, value); !ok {
+			return true
+		}
+		if index >= offset {
+			if info, err := header.GetTokenInfo(key); err == nil {
+				tokens = append(tokens, *info)
+			}
+		}
+		index++
+		return len(tokens) < limit
+	})
+	return tokens, err
+}
+
+// 遍历StatTree分页返回Token的持有人, 包括合约账户
+func (header Header) ListTokenHolders(address []byte, offset, limit int) ([]TokenHolder, error) {
+	tokenAddress := hex.EncodeToString(address)
+	holders := make([]TokenHolder, 0)
+	index := 0
+	add := func(holder []byte, amount types.Amount) bool {
+		if amount.Sign() <= 0 {
+			return true
+		}
+		if index >= offset {
+			holders = append(holders, TokenHolder{Address: holder, Amount: amount, Frozen: header.IsFrozen(tokenAddress, holder)})
+		}
+		index++
+		return len(holders) < limit
+	}
+	err := header.StatTree.Iterate(func(key, value []byte) bool {
+		var account types.Account
+		if err := json.Unmarshal(value, &account); err != nil {
+			return true
+		}
+		if !add(account.Address, account.Balances[tokenAddress]) {
+			return false
+		}
+		contracts := make([]string, 0, len(account.Contracts))
+		for contractAddr := range account.Contracts {
+			contracts = append(contracts, contractAddr)
+		}
+		sort.Strings(contracts)
+		for _, contractAddr := range contracts {
+			contractAccount := account.Contracts[contractAddr]
+			if !add(append(append([]byte{}, account.Address...), contractAccount.Address...), contractAccount.Balances[tokenAddress]) {
+				return false
+			}
+		}
+		return true
+	})
+	return holders, err
+}
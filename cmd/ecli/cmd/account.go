@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"bufio"
 	"encoding/hex"
 	"fmt"
+	"os"
 
 	"github.com/EducationEKT/EKT/core/types"
 	"github.com/EducationEKT/EKT/crypto"
@@ -23,6 +25,11 @@ func init() {
 			Short: "Generate public key and private key.",
 			Run:   NewAccount,
 		},
+		&cobra.Command{
+			Use:   "external",
+			Short: "Generate external address of a foreign public chain user.",
+			Run:   ExternalAddress,
+		},
 	}...)
 }
 
@@ -31,3 +38,24 @@ func NewAccount(cmd *cobra.Command, args []string) {
 	fmt.Println("Please save your Private Key: ", hex.EncodeToString(privateKey))
 	fmt.Println("Your address is: ", hex.EncodeToString(types.FromPubKeyToAddress(pubKey)))
 }
+
+func ExternalAddress(cmd *cobra.Command, args []string) {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Input the public chain id: ")
+	input.Scan()
+	publicChainId := input.Text()
+	fmt.Print("Input the address on this public chain: ")
+	input.Scan()
+	address, err := hex.DecodeString(input.Text())
+	if err != nil {
+		fmt.Println("Error address")
+		os.Exit(-1)
+	}
+	external, err := types.NewExternalAddress(publicChainId, address)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	fmt.Println("External address is: ", hex.EncodeToString(external.Bytes()))
+	fmt.Println("Text format is: ", external.String())
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EducationEKT/EKT/blockchain"
+	"github.com/EducationEKT/EKT/contract"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/crypto"
+
+	"github.com/spf13/cobra"
+)
+
+var CrossChainCmd *cobra.Command
+
+func init() {
+	CrossChainCmd = &cobra.Command{
+		Use:   "crosschain",
+		Short: "cross chain operate",
+	}
+	CrossChainCmd.AddCommand([]*cobra.Command{
+		&cobra.Command{
+			Use:   "approve",
+			Short: "Approve the registration of a public chain with delegate private key.",
+			Run:   ApproveCrossChain,
+		},
+	}...)
+}
+
+// 委托人核对外部公链的注册请求之后, 使用自己的私钥签名同意注册的交易
+func ApproveCrossChain(cmd *cobra.Command, args []string) {
+	fmt.Print("Input your delegate private key: ")
+	input := bufio.NewScanner(os.Stdin)
+	input.Scan()
+	privKey, err := hex.DecodeString(strings.TrimPrefix(input.Text(), "0x"))
+	if err != nil {
+		fmt.Println("Your private key is not right, exit.")
+		os.Exit(-1)
+	}
+	pubKey, err := crypto.PubKey(privKey)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	from := types.FromPubKeyToAddress(pubKey)
+
+	op := blockchain.CrossChainOp{Op: blockchain.CROSSCHAIN_OP_REGIST}
+	fmt.Print("Input public chain id: ")
+	input.Scan()
+	op.PublicChainId = input.Text()
+	fmt.Print("Input token id: ")
+	input.Scan()
+	op.TokenId = input.Text()
+	fmt.Print("Input operation code id: ")
+	input.Scan()
+	op.OpCodeId = input.Text()
+	fmt.Print("Input delegates of the public chain, separated by comma (Press ENTER if none): ")
+	input.Scan()
+	for _, delegate := range strings.Split(input.Text(), ",") {
+		if delegate = strings.TrimSpace(delegate); delegate == "" {
+			continue
+		}
+		address, err := hex.DecodeString(delegate)
+		if err != nil {
+			fmt.Println("Error delegate address")
+			os.Exit(-1)
+		}
+		op.Delegates = append(op.Delegates, address)
+	}
+	fmt.Print("Input the public chain id of EKT used by the public chain (Press ENTER if it is EKT): ")
+	input.Scan()
+	op.LocalChainId = input.Text()
+	fmt.Print("Input the chain id in block votes of the public chain (Press ENTER if it is 1): ")
+	input.Scan()
+	if text := strings.TrimSpace(input.Text()); text != "" {
+		blockchainId, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			fmt.Println("Error chain id")
+			os.Exit(-1)
+		}
+		op.BlockchainId = blockchainId
+	}
+	if op.PublicChainId == "" || op.OpCodeId == "" {
+		fmt.Println("Public chain id and operation code id are required, exit.")
+		os.Exit(-1)
+	}
+
+	data, _ := json.Marshal(op)
+	to, _ := hex.DecodeString(contract.SYSTEM_AUTHOR + contract.CROSSCHAIN_CONTRACT)
+	nonce := getAccountNonce(hex.EncodeToString(from))
+	tx := userevent.NewTransaction(from, to, time.Now().UnixNano()/1e6, 0, 0, nonce, string(data), types.EKTAddress)
+	userevent.SignTransaction(tx, privKey)
+	sendTransaction(*tx)
+}
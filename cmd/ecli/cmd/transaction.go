@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/EducationEKT/EKT/cmd/ecli/param"
@@ -67,10 +68,9 @@ func SendTransaction(cmd *cobra.Command, args []string) {
 		fmt.Println("You can only input int64 only, exit.")
 		os.Exit(-1)
 	}
-	fmt.Print("Input the address who receive this token: ")
+	fmt.Print("Input the address who receive this token (publicChainId:address for external address): ")
 	input.Scan()
-	receive := input.Text()
-	to, err := hex.DecodeString(receive)
+	to, err := parseReceiver(input.Text())
 	if err != nil {
 		fmt.Println("Error address")
 		os.Exit(-1)
@@ -84,6 +84,18 @@ func SendTransaction(cmd *cobra.Command, args []string) {
 	sendTransaction(*tx)
 }
 
+// 接收地址可以是hex格式的地址, 也可以是publicChainId:address格式的外部地址
+func parseReceiver(receive string) ([]byte, error) {
+	if strings.Contains(receive, ":") {
+		external, err := types.ParseExternalAddress(receive)
+		if err != nil {
+			return nil, err
+		}
+		return external.Bytes(), nil
+	}
+	return hex.DecodeString(receive)
+}
+
 func BenchTest(cmd *cobra.Command, args []string) {
 	fmt.Print("Input your private key: ")
 	input := bufio.NewScanner(os.Stdin)
@@ -12,7 +12,7 @@ var (
 )
 
 func init() {
-	cmds = append(cmds, cmd.TransactionCmd, cmd.AccountCmd, cmd.ContractCmd)
+	cmds = append(cmds, cmd.TransactionCmd, cmd.AccountCmd, cmd.ContractCmd, cmd.CrossChainCmd)
 }
 
 func main() {
@@ -90,8 +90,9 @@ func InitService(confPath string) error {
 		return err
 	}
 
-	// 初始化委托人节点
+	// 初始化委托人节点和协议升级计划
 	param.InitBootNodes()
+	param.InitForks()
 
 	// 初始化ektClient
 	ektclient.InitEKTClient()
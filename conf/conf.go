@@ -8,15 +8,33 @@ import (
 )
 
 type EKTConf struct {
-	Version              string          `json:"version"`
-	DBPath               string          `json:"dbPath"`
-	LogPath              string          `json:"logPath"`
-	Debug                bool            `json:"debug"`
-	Node                 types.Peer      `json:"node"`
-	BlockchainManagePwd  string          `json:"blockchainManagePwd"`
+	Version              string           `json:"version"`
+	DBPath               string           `json:"dbPath"`
+	LogPath              string           `json:"logPath"`
+	Debug                bool             `json:"debug"`
+	Node                 types.Peer       `json:"node"`
+	BlockchainManagePwd  string           `json:"blockchainManagePwd"`
+	GenesisBlockAccounts []types.Account  `json:"genesisBlock"`
+	PrivateKey           types.HexBytes   `json:"privateKey"`
+	Env                  string           `json:"env"`
+	Peers                []types.Peer     `json:"peers"`
+	Chains               []ChainConf      `json:"chains"`
+	PublicChainId        string           `json:"publicChainId"`
+	CrossChains          []CrossChainConf `json:"crossChains"`
+}
+
+// 同一个节点中运行的其他链，每条链有自己的创世块和委托人，主链仍然使用上面的配置
+type ChainConf struct {
+	ChainId              int64           `json:"chainId"`
 	GenesisBlockAccounts []types.Account `json:"genesisBlock"`
-	PrivateKey           types.HexBytes  `json:"privateKey"`
-	Env                  string          `json:"env"`
+	Delegates            []types.Peer    `json:"delegates"`
+}
+
+// 已经注册跨链的外部公链, 中继把发往这条公链的跨链交易提交给Peers
+type CrossChainConf struct {
+	PublicChainId string       `json:"publicChainId"`
+	ChainId       int64        `json:"chainId"`
+	Peers         []types.Peer `json:"peers"`
 }
 
 var EKTConfig *EKTConf
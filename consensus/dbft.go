@@ -30,7 +30,7 @@ type DbftConsensus struct {
 
 func NewDbftConsensus(Blockchain *blockchain.BlockChain, client ektclient.IClient) *DbftConsensus {
 	return &DbftConsensus{
-		Round:        types.NewRound(param.MainChainDelegateNode),
+		Round:        types.NewRound(param.ChainDelegates(Blockchain.ChainId)),
 		Blockchain:   Blockchain,
 		BlockManager: blockchain.NewBlockManager(),
 		VoteResults:  blockchain.NewVoteResults(),
@@ -40,8 +40,16 @@ func NewDbftConsensus(Blockchain *blockchain.BlockChain, client ektclient.IClien
 	}
 }
 
+// 当前参与出块的委托人数量由链上的共识参数决定
 func (dbft DbftConsensus) GetRound() types.Round {
-	return dbft.Round.Clone()
+	round := dbft.Round.Clone()
+	round.Peers = dbft.Blockchain.ChainParams().ActiveDelegates(round.Peers)
+	return round
+}
+
+// 出块间隔由链上的共识参数决定
+func (dbft DbftConsensus) interval() time.Duration {
+	return dbft.Blockchain.ChainParams().Interval()
 }
 
 // 校验从其他委托人节点过来的区块数据
@@ -53,7 +61,7 @@ func (dbft DbftConsensus) BlockFromPeer(clog *ctxlog.ContextLog, block *blockcha
 	// 判断此区块是否是一个interval之前打包的，如果是则放弃vote
 	// unit： ms    单位：ms
 	now := time.Now().UnixNano() / 1e6
-	endTime := header.Timestamp + int64(blockchain.BackboneBlockInterval/1e6)
+	endTime := header.Timestamp + int64(dbft.interval()/1e6)
 	if now > endTime {
 		clog.Log("ValidateTime", now)
 		clog.Log("EndTime", endTime)
@@ -71,7 +79,7 @@ func (dbft DbftConsensus) BlockFromPeer(clog *ctxlog.ContextLog, block *blockcha
 	}
 
 	if status == blockchain.BLOCK_VALID {
-		if lastVoteTime := dbft.BlockManager.GetVoteTime(block.GetHeader().Height); lastVoteTime+int64(blockchain.BackboneBlockInterval)/1e6 > time.Now().UnixNano()/1e6 {
+		if lastVoteTime := dbft.BlockManager.GetVoteTime(block.GetHeader().Height); lastVoteTime+int64(dbft.interval())/1e6 > time.Now().UnixNano()/1e6 {
 			clog.Log("Voted this height", true)
 			return
 		}
@@ -95,12 +103,15 @@ func (dbft DbftConsensus) BlockFromPeer(clog *ctxlog.ContextLog, block *blockcha
 	clog.Log("txs", transactions)
 	clog.Log("receipts", receipts)
 	// 对区块进行validate和recover，如果区块数据没问题，则发送投票给其他节点
-	if dbft.Blockchain.ValidateBlock(*block) {
+	// 本地执行合约超时时不投票也不标记为错误区块, 收到区块时重新校验
+	if err := dbft.Blockchain.ValidateBlock(*block); err == nil {
 		if dbft.SendVote(*header) {
 			dbft.BlockManager.SetVoteTime(block.GetHeader().Height, time.Now().UnixNano()/1e6)
 			dbft.BlockManager.SetBlockStatus(header.CalculateHash(), blockchain.BLOCK_VOTED)
 			clog.Log("SendVote", true)
 		}
+	} else if err == blockchain.ErrValidateTimeout {
+		clog.Log("validate timeout", true)
 	} else {
 		clog.Log("error body", true)
 		dbft.BlockManager.SetBlockStatus(header.CalculateHash(), blockchain.BLOCK_ERROR_BODY)
@@ -116,7 +127,7 @@ func (dbft DbftConsensus) SendVote(header blockchain.Header) bool {
 		// 距离投票的毫秒数
 		intervalInFact := int(time.Now().UnixNano()/1e6 - lastVoteTime)
 		// 规则指定的毫秒数
-		intervalInRule := int(blockchain.BackboneBlockInterval / 1e6)
+		intervalInRule := int(dbft.interval() / 1e6)
 
 		// 说明在一个intervalInRule内进行过投票
 		if intervalInFact < intervalInRule {
@@ -178,10 +189,10 @@ func (dbft DbftConsensus) TryPack() bool {
 
 	round := dbft.GetRound()
 	distance := round.Distance(hex.EncodeToString(lastHeader.Coinbase), conf.EKTConfig.Node.Account)
-	t := int64(distance) * int64(blockchain.BackboneBlockInterval) / 1e6
+	t := int64(distance) * int64(dbft.interval()) / 1e6
 
 	lastTime := lastHeader.Timestamp
-	roundTime := int64(time.Duration(round.Len())*blockchain.BackboneBlockInterval) / 1e6
+	roundTime := int64(time.Duration(round.Len())*dbft.interval()) / 1e6
 
 	nextTime := lastTime + t
 	for i := 0; nextTime >= time.Now().UnixNano()/1e6; i++ {
@@ -196,7 +207,7 @@ func (dbft DbftConsensus) TryPack() bool {
 
 func (dbft DbftConsensus) orderliness(packTime int64) {
 	dbft.once.Do(func() {
-		roundTime := int64(dbft.GetRound().Len()) * int64(blockchain.BackboneBlockInterval) / 1e6
+		roundTime := int64(dbft.GetRound().Len()) * int64(dbft.interval()) / 1e6
 		gap := 100 * time.Millisecond
 		for {
 			now := time.Now().UnixNano() / 1e6
@@ -205,7 +216,7 @@ func (dbft DbftConsensus) orderliness(packTime int64) {
 			}
 			if int64(math.Abs(float64(now-packTime))) < int64(gap/time.Millisecond) {
 				go dbft.Pack(packTime)
-				time.Sleep(blockchain.BackboneBlockInterval)
+				time.Sleep(dbft.interval())
 			} else {
 				time.Sleep(gap)
 			}
@@ -220,7 +231,7 @@ func (dbft DbftConsensus) ValidatePackRight(packTimeMs, lastBlockTimeMs int64, l
 	}
 
 	distance := int64(round.Distance(lastMiner, miner))
-	interval := int64(blockchain.BackboneBlockInterval / 1e6)
+	interval := int64(dbft.interval() / 1e6)
 	roundTime := interval * int64(round.Len())
 
 	return (packTimeMs-lastBlockTimeMs)%roundTime == distance*interval
@@ -228,7 +239,7 @@ func (dbft DbftConsensus) ValidatePackRight(packTimeMs, lastBlockTimeMs int64, l
 
 func (dbft DbftConsensus) CheckPackInterval() bool {
 	lastHeader := dbft.Blockchain.LastHeader()
-	result := dbft.BlockManager.CheckHeightInterval(lastHeader.Height+1, int64(blockchain.BackboneBlockInterval))
+	result := dbft.BlockManager.CheckHeightInterval(lastHeader.Height+1, int64(lastHeader.ChainParams().Interval()))
 	if result {
 		dbft.BlockManager.SetBlockStatusByHeight(lastHeader.Height+1, time.Now().UnixNano()/1e6)
 	}
@@ -270,7 +281,7 @@ func (dbft DbftConsensus) RecoverFromDB() {
 	// 如果是第一次打开
 	if header == nil {
 		// 将创世块写入数据库
-		accounts := conf.EKTConfig.GenesisBlockAccounts
+		accounts := param.GenesisAccounts(dbft.Blockchain.ChainId)
 		block := blockchain.CreateGenesisBlock(accounts)
 		header = block.GetHeader()
 		dbft.SaveBlock(&block, nil)
@@ -344,9 +355,12 @@ func (dbft DbftConsensus) SyncHeight(height int64) bool {
 		log.Info("Get header by hash failed, hash = %s", hex.EncodeToString(block.Hash))
 		return false
 	} else {
-		if dbft.Blockchain.ValidateBlock(*block) {
+		// 本地执行合约超时时同步失败, 之后重新同步这个高度
+		if err := dbft.Blockchain.ValidateBlock(*block); err == nil {
 			dbft.SaveBlock(block, nil)
 			return true
+		} else if err == blockchain.ErrValidateTimeout {
+			log.Info("Validate block at height %d timeout, retry later", height)
 		}
 	}
 	return false
@@ -421,8 +435,13 @@ func (dbft DbftConsensus) ValidateVotes(votes blockchain.Votes) bool {
 	if !votes.Validate() {
 		return false
 	}
+	for _, vote := range votes {
+		if vote.Vote.BlockchainId != dbft.Blockchain.ChainId {
+			return false
+		}
+	}
 
-	if votes.Len() <= dbft.GetRound().Len()/2 {
+	if !dbft.Blockchain.ChainParams().Majority(votes.Len(), dbft.GetRound().Len()) {
 		return false
 	}
 	return true
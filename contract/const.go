@@ -3,6 +3,13 @@ package contract
 const (
 	SYSTEM_AUTHOR           = "0000000000000000000000000000000000000000000000000000000000000000"
 	EKT_GAS_BANCOR_CONTRACT = "0000000000000000000000000000000000000000000000000000000000000001"
+	MULTISIG_CONTRACT       = "0000000000000000000000000000000000000000000000000000000000000002"
+	KEY_ROTATION_CONTRACT   = "0000000000000000000000000000000000000000000000000000000000000003"
+	TOKEN_ISSUE_CONTRACT    = "0000000000000000000000000000000000000000000000000000000000000004"
+	TOKEN_ADMIN_CONTRACT    = "0000000000000000000000000000000000000000000000000000000000000005"
+	BANCOR_FACTORY_CONTRACT = "0000000000000000000000000000000000000000000000000000000000000006"
+	GOVERNANCE_CONTRACT     = "0000000000000000000000000000000000000000000000000000000000000007"
+	CROSSCHAIN_CONTRACT     = "0000000000000000000000000000000000000000000000000000000000000008"
 )
 
 const (
@@ -14,6 +14,8 @@ type ChainReader interface {
 
 	GetTimestamp() int64
 
+	GetHeight() int64
+
 	GetParent() []byte
 }
 
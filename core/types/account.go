@@ -8,48 +8,38 @@ import (
 
 type Account struct {
 	Address   HexBytes                   `json:"address"`
-	Amount    int64                      `json:"amount"`
-	Gas       int64                      `json:"gas"`
+	Amount    Amount                     `json:"amount"`
+	Gas       Amount                     `json:"gas"`
 	Nonce     int64                      `json:"nonce"`
 	Contracts map[string]ContractAccount `json:"contracts"`
-	Balances  map[string]int64           `json:"balances"`
+	Balances  map[string]Amount          `json:"balances"`
+	MultiSig  *MultiSig                  `json:"multiSig,omitempty"`
+	AuthKey   HexBytes                   `json:"authKey,omitempty"` // 轮换之后的公钥hash, 为空时使用地址对应的公钥签名
 }
 
 type AccountChange struct {
-	M map[string]int64
+	M map[string]Amount
 }
 
 func NewAccountChange() *AccountChange {
 	return &AccountChange{
-		M: make(map[string]int64),
+		M: make(map[string]Amount),
 	}
 }
 
-func (change *AccountChange) Add(tokenAddress string, amount int64) {
-	value, exist := change.M[tokenAddress]
-	if !exist {
-		value = 0
-	}
-	value = value + amount
-	change.M[tokenAddress] = value
+func (change *AccountChange) Add(tokenAddress string, amount Amount) {
+	change.M[tokenAddress] = change.M[tokenAddress].Add(amount)
 }
 
-func (change *AccountChange) Reduce(tokenAddress string, amount int64) {
-	value, exist := change.M[tokenAddress]
-	if !exist {
-		value = 0
-	}
-	value = value - amount
-	change.M[tokenAddress] = value
+func (change *AccountChange) Reduce(tokenAddress string, amount Amount) {
+	change.M[tokenAddress] = change.M[tokenAddress].Sub(amount)
 }
 
 func NewAccount(address []byte) *Account {
 	return &Account{
 		Address:   address,
 		Nonce:     0,
-		Gas:       0,
-		Amount:    0,
-		Balances:  make(map[string]int64),
+		Balances:  make(map[string]Amount),
 		Contracts: make(map[string]ContractAccount),
 	}
 }
@@ -63,15 +53,23 @@ func (account Account) GetNonce() int64 {
 	return account.Nonce
 }
 
-func (account Account) GetAmount() int64 {
+func (account Account) GetAmount() Amount {
 	return account.Amount
 }
 
 func (account *Account) BurnGas(gas int64) {
-	account.Gas = account.Gas - gas
+	account.Gas = account.Gas.Sub(NewAmount(gas))
 	account.Nonce++
 }
 
+// 交易签名对应的公钥hash需要等于AuthorizedKey
+func (account Account) AuthorizedKey() []byte {
+	if len(account.AuthKey) != 0 {
+		return account.AuthKey
+	}
+	return account.Address
+}
+
 func FromPubKeyToAddress(pubKey []byte) []byte {
 	hash := crypto.Sha3_256(pubKey)
 	address := crypto.Sha3_256(crypto.Sha3_256(append([]byte("EKT"), hash...)))
@@ -79,31 +77,56 @@ func FromPubKeyToAddress(pubKey []byte) []byte {
 }
 
 func (account *Account) Transfer(change AccountChange) bool {
-	for tokenAddr, amount := range change.M {
+	return transfer(&account.Amount, &account.Gas, &account.Balances, change)
+}
+
+// 所有余额都在int64范围之内, 旧版本的区块不允许超出int64的余额
+func (account Account) FitsInt64() bool {
+	if !balancesFitInt64(account.Amount, account.Gas, account.Balances) {
+		return false
+	}
+	for _, contractAccount := range account.Contracts {
+		if !balancesFitInt64(contractAccount.Amount, contractAccount.Gas, contractAccount.Balances) {
+			return false
+		}
+	}
+	return true
+}
+
+// 把change应用到余额上, 任意一个余额小于0或者超出MaxAmount时返回false
+func transfer(amount, gas *Amount, balances *map[string]Amount, change AccountChange) bool {
+	for tokenAddr, value := range change.M {
+		var balance *Amount
 		switch tokenAddr {
 		case EKTAddress:
-			account.Amount += amount
-			if account.Amount < 0 {
-				return false
-			}
+			balance = amount
 		case GasAddress:
-			account.Gas += amount
-			if account.Gas < 0 {
-				return false
-			}
+			balance = gas
 		default:
-			if account.Balances == nil {
-				account.Balances = make(map[string]int64)
-			}
-			count, exist := account.Balances[tokenAddr]
-			if !exist {
-				count = 0
+			if *balances == nil {
+				*balances = make(map[string]Amount)
 			}
-			count += amount
-			if count < 0 {
-				return false
-			}
-			account.Balances[tokenAddr] = count
+			count := (*balances)[tokenAddr]
+			balance = &count
+		}
+		*balance = balance.Add(value)
+		if balance.Sign() < 0 || !balance.Valid() {
+			return false
+		}
+		if tokenAddr != EKTAddress && tokenAddr != GasAddress {
+			(*balances)[tokenAddr] = *balance
+		}
+	}
+	return true
+}
+
+func balancesFitInt64(amount, gas Amount, balances map[string]Amount) bool {
+	if !amount.IsInt64() || !gas.IsInt64() {
+		return false
+	}
+	for _, balance := range balances {
+		if !balance.IsInt64() {
+			return false
 		}
 	}
 	return true
@@ -0,0 +1,160 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// 2^256-1的十进制位数
+	MaxAmountDigits = 78
+)
+
+var (
+	MaxAmount = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// Amount是不可变的任意精度整数，用来表示账户余额和交易金额
+// json编码为数字, 在int64范围之内时和之前的int64编码完全相同, 所以不影响已有交易和账户的hash
+type Amount struct {
+	v *big.Int
+}
+
+func NewAmount(value int64) Amount {
+	return Amount{v: big.NewInt(value)}
+}
+
+func NewAmountFromBig(value *big.Int) Amount {
+	if value == nil {
+		return Amount{}
+	}
+	return Amount{v: new(big.Int).Set(value)}
+}
+
+// 解析十进制的金额, 支持科学计数法, 结果必须是整数
+func ParseAmount(s string) (Amount, error) {
+	mantissa, exp := strings.TrimSpace(s), int64(0)
+	if i := strings.IndexAny(mantissa, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(mantissa[i+1:], 10, 32)
+		if err != nil {
+			return Amount{}, ErrInvalidAmount
+		}
+		mantissa, exp = mantissa[:i], e
+	}
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		frac := mantissa[i+1:]
+		mantissa = mantissa[:i] + frac
+		exp -= int64(len(frac))
+	}
+	if len(mantissa) == 0 || len(mantissa) > 2*MaxAmountDigits || exp > MaxAmountDigits || exp < -2*MaxAmountDigits {
+		return Amount{}, ErrInvalidAmount
+	}
+	value, ok := new(big.Int).SetString(mantissa, 10)
+	if !ok {
+		return Amount{}, ErrInvalidAmount
+	}
+	if exp >= 0 {
+		value.Mul(value, new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil))
+	} else {
+		divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(-exp), nil)
+		quo, mod := new(big.Int).QuoRem(value, divisor, new(big.Int))
+		if mod.Sign() != 0 {
+			return Amount{}, ErrInvalidAmount
+		}
+		value = quo
+	}
+	amount := Amount{v: value}
+	if !amount.Valid() {
+		return Amount{}, ErrInvalidAmount
+	}
+	return amount, nil
+}
+
+func (amount Amount) big() *big.Int {
+	if amount.v == nil {
+		return new(big.Int)
+	}
+	return amount.v
+}
+
+// 返回一个拷贝, 修改返回值不影响Amount本身
+func (amount Amount) Big() *big.Int {
+	return new(big.Int).Set(amount.big())
+}
+
+func (amount Amount) Add(other Amount) Amount {
+	return Amount{v: new(big.Int).Add(amount.big(), other.big())}
+}
+
+func (amount Amount) Sub(other Amount) Amount {
+	return Amount{v: new(big.Int).Sub(amount.big(), other.big())}
+}
+
+func (amount Amount) Mul(other Amount) Amount {
+	return Amount{v: new(big.Int).Mul(amount.big(), other.big())}
+}
+
+func (amount Amount) Neg() Amount {
+	return Amount{v: new(big.Int).Neg(amount.big())}
+}
+
+func (amount Amount) Cmp(other Amount) int {
+	return amount.big().Cmp(other.big())
+}
+
+func (amount Amount) Sign() int {
+	return amount.big().Sign()
+}
+
+func (amount Amount) IsZero() bool {
+	return amount.Sign() == 0
+}
+
+// 绝对值不超过MaxAmount
+func (amount Amount) Valid() bool {
+	return new(big.Int).Abs(amount.big()).Cmp(MaxAmount) <= 0
+}
+
+func (amount Amount) IsInt64() bool {
+	return amount.big().IsInt64()
+}
+
+func (amount Amount) Int64() int64 {
+	return amount.big().Int64()
+}
+
+func (amount Amount) String() string {
+	return amount.big().String()
+}
+
+func (amount Amount) MarshalJSON() ([]byte, error) {
+	return []byte(amount.String()), nil
+}
+
+// 同时支持数字和字符串两种格式
+func (amount *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*amount = Amount{}
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	value, err := ParseAmount(string(data))
+	if err != nil {
+		return err
+	}
+	*amount = value
+	return nil
+}
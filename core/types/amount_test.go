@@ -0,0 +1,51 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAmount_JSON(t *testing.T) {
+	for _, c := range []struct {
+		input  string
+		output string
+	}{
+		{`100`, `100`},
+		{`-5`, `-5`},
+		{`"123456789012345678901234567890"`, `123456789012345678901234567890`},
+		{`1e21`, `1000000000000000000000`},
+		{`1.5e3`, `1500`},
+		{`null`, `0`},
+	} {
+		var amount Amount
+		if err := json.Unmarshal([]byte(c.input), &amount); err != nil {
+			t.Fatal(c.input, err)
+		}
+		data, _ := json.Marshal(amount)
+		if string(data) != c.output {
+			t.Fatalf("%s: expect %s, got %s", c.input, c.output, string(data))
+		}
+	}
+
+	for _, input := range []string{`1.5`, `1e-3`, `"abc"`, `1e100`} {
+		var amount Amount
+		if err := json.Unmarshal([]byte(input), &amount); err == nil {
+			t.Fatal("invalid amount accepted", input)
+		}
+	}
+}
+
+func TestAccount_TransferOverflow(t *testing.T) {
+	account := NewAccount([]byte("address"))
+	change := NewAccountChange()
+	change.Add(EKTAddress, NewAmount(1<<62))
+	change.Add(EKTAddress, NewAmount(1<<62))
+	if !account.Transfer(*change) || account.Amount.IsInt64() || account.FitsInt64() {
+		t.Fatal("big amount is not represented")
+	}
+	change = NewAccountChange()
+	change.Reduce(EKTAddress, account.Amount.Add(NewAmount(1)))
+	if account.Transfer(*change) {
+		t.Fatal("negative balance accepted")
+	}
+}
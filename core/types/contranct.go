@@ -50,53 +50,30 @@ func (contractData ContractData) Bytes() []byte {
 	return result
 }
 
+const (
+	NativeBancor = "bancor"
+)
+
 type ContractAccount struct {
-	Address      HexBytes         `json:"address"`
-	Amount       int64            `json:"amount"`
-	Gas          int64            `json:"gas"`
-	CodeHash     HexBytes         `json:"codeHash"`
-	ContractData ContractData     `json:"data"`
-	Balances     map[string]int64 `json:"balances"`
+	Address      HexBytes          `json:"address"`
+	Amount       Amount            `json:"amount"`
+	Gas          Amount            `json:"gas"`
+	CodeHash     HexBytes          `json:"codeHash"`
+	ContractData ContractData      `json:"data"`
+	Balances     map[string]Amount `json:"balances"`
+	Native       string            `json:"native,omitempty"`      // 不为空时表示由链直接执行的原生合约, 例如NativeBancor
+	StorageRoot  HexBytes          `json:"storageRoot,omitempty"` // 合约存储的MPT根节点, 为空时表示没有存储
 }
 
 func NewContractAccount(address []byte, contractHash []byte, contractData ContractData) *ContractAccount {
 	return &ContractAccount{
 		Address:      address,
-		Amount:       0,
-		Gas:          0,
-		Balances:     make(map[string]int64),
+		Balances:     make(map[string]Amount),
 		CodeHash:     contractHash,
 		ContractData: contractData,
 	}
 }
 
 func (account *ContractAccount) Transfer(change AccountChange) bool {
-	for tokenAddr, amount := range change.M {
-		switch tokenAddr {
-		case EKTAddress:
-			account.Amount += amount
-			if account.Amount < 0 {
-				return false
-			}
-		case GasAddress:
-			account.Gas += amount
-			if account.Gas < 0 {
-				return false
-			}
-		default:
-			if account.Balances == nil {
-				account.Balances = make(map[string]int64)
-			}
-			count, exist := account.Balances[tokenAddr]
-			if !exist {
-				count = 0
-			}
-			count += amount
-			if count < 0 {
-				return false
-			}
-			account.Balances[tokenAddr] = count
-		}
-	}
-	return true
+	return transfer(&account.Amount, &account.Gas, &account.Balances, change)
 }
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const ExternalAddressLength = 68
+
+var ErrInvalidExternalAddress = errors.New("invalid external address")
+
+// 外部地址是外部公链用户在EKT中的长地址, 共68个字节
+// 前4个字节分别用2个字节存储公链id和内部地址的长度, 之后依次是公链id和用户在这条公链上的内部地址, 中间用0x00填充
+type ExternalAddress struct {
+	PublicChainId string   `json:"publicChainId"`
+	Address       HexBytes `json:"address"`
+}
+
+func NewExternalAddress(publicChainId string, address []byte) (*ExternalAddress, error) {
+	external := &ExternalAddress{PublicChainId: publicChainId, Address: address}
+	if !external.Valid() {
+		return nil, ErrInvalidExternalAddress
+	}
+	return external, nil
+}
+
+func (external ExternalAddress) Valid() bool {
+	return len(external.PublicChainId) > 0 && len(external.Address) > 0 &&
+		len(external.PublicChainId)+len(external.Address) <= ExternalAddressLength-4
+}
+
+func (external ExternalAddress) Bytes() []byte {
+	data := make([]byte, ExternalAddressLength)
+	binary.BigEndian.PutUint16(data[0:2], uint16(len(external.PublicChainId)))
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(external.Address)))
+	copy(data[4:], external.PublicChainId)
+	copy(data[ExternalAddressLength-len(external.Address):], external.Address)
+	return data
+}
+
+// 外部地址的文本格式: publicChainId:内部地址的hex
+func (external ExternalAddress) String() string {
+	return external.PublicChainId + ":" + hex.EncodeToString(external.Address)
+}
+
+func DecodeExternalAddress(data []byte) (*ExternalAddress, error) {
+	if len(data) != ExternalAddressLength {
+		return nil, ErrInvalidExternalAddress
+	}
+	chainIdLength, addressLength := int(binary.BigEndian.Uint16(data[0:2])), int(binary.BigEndian.Uint16(data[2:4]))
+	if chainIdLength == 0 || addressLength == 0 || chainIdLength+addressLength > ExternalAddressLength-4 {
+		return nil, ErrInvalidExternalAddress
+	}
+	// 公链id和内部地址之间必须全部是0x00
+	for _, b := range data[4+chainIdLength : ExternalAddressLength-addressLength] {
+		if b != 0 {
+			return nil, ErrInvalidExternalAddress
+		}
+	}
+	return NewExternalAddress(string(data[4:4+chainIdLength]), data[ExternalAddressLength-addressLength:])
+}
+
+func IsExternalAddress(data []byte) bool {
+	_, err := DecodeExternalAddress(data)
+	return err == nil
+}
+
+// 解析publicChainId:hex格式的外部地址
+func ParseExternalAddress(text string) (*ExternalAddress, error) {
+	index := strings.LastIndex(text, ":")
+	if index <= 0 {
+		return nil, ErrInvalidExternalAddress
+	}
+	address, err := hex.DecodeString(text[index+1:])
+	if err != nil {
+		return nil, ErrInvalidExternalAddress
+	}
+	return NewExternalAddress(text[:index], address)
+}
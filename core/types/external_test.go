@@ -0,0 +1,34 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExternalAddress(t *testing.T) {
+	inner := bytes.Repeat([]byte{0xab}, 20)
+	external, err := NewExternalAddress("000000000FFFFFFFF00000001", inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := external.Bytes()
+	if len(data) != ExternalAddressLength || data[1] != 25 || data[3] != 20 {
+		t.Fatal("encode mismatch", data)
+	}
+	decoded, err := DecodeExternalAddress(data)
+	if err != nil || decoded.PublicChainId != external.PublicChainId || !bytes.Equal(decoded.Address, inner) {
+		t.Fatal("decode mismatch", decoded, err)
+	}
+	parsed, err := ParseExternalAddress(external.String())
+	if err != nil || !bytes.Equal(parsed.Bytes(), data) {
+		t.Fatal("parse mismatch", parsed, err)
+	}
+
+	data[40] = 1
+	if IsExternalAddress(data) {
+		t.Fatal("padding must be zero")
+	}
+	if _, err := NewExternalAddress("chain", bytes.Repeat([]byte{1}, 60)); err == nil {
+		t.Fatal("too long address accepted")
+	}
+}
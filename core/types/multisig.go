@@ -0,0 +1,70 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/EducationEKT/EKT/crypto"
+)
+
+const (
+	// 多签账户最多的成员数量
+	MaxMultiSigMembers = 16
+)
+
+// 多签账户的授权信息，交易需要至少Threshold个成员签名
+type MultiSig struct {
+	Members   []HexBytes `json:"members"`
+	Threshold int        `json:"threshold"`
+}
+
+func NewMultiSig(members [][]byte, threshold int) *MultiSig {
+	multiSig := &MultiSig{
+		Members:   make([]HexBytes, 0, len(members)),
+		Threshold: threshold,
+	}
+	for _, member := range members {
+		multiSig.Members = append(multiSig.Members, member)
+	}
+	sort.Slice(multiSig.Members, func(i, j int) bool {
+		return bytes.Compare(multiSig.Members[i], multiSig.Members[j]) < 0
+	})
+	return multiSig
+}
+
+func (multiSig MultiSig) Valid() bool {
+	if len(multiSig.Members) == 0 || len(multiSig.Members) > MaxMultiSigMembers {
+		return false
+	}
+	if multiSig.Threshold < 1 || multiSig.Threshold > len(multiSig.Members) {
+		return false
+	}
+	for i, member := range multiSig.Members {
+		if len(member) != AccountAddressLength {
+			return false
+		}
+		if i > 0 && bytes.Compare(multiSig.Members[i-1], member) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (multiSig MultiSig) IsMember(address []byte) bool {
+	for _, member := range multiSig.Members {
+		if bytes.Equal(member, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// 多签账户的地址由排好序的成员地址和阈值计算得出，不对应任何私钥
+func (multiSig MultiSig) Address() []byte {
+	data := []byte(fmt.Sprintf("EKT-MULTISIG-%d", multiSig.Threshold))
+	for _, member := range multiSig.Members {
+		data = append(data, member...)
+	}
+	return crypto.Sha3_256(crypto.Sha3_256(data))
+}
@@ -1,18 +1,42 @@
 package types
 
 import (
+	"bytes"
 	"encoding/json"
+	"regexp"
+	"strings"
 
 	"github.com/EducationEKT/EKT/crypto"
 )
 
+const (
+	MaxTokenDecimals = 18
+)
+
+var tokenSymbolRegexp = regexp.MustCompile(`^[A-Za-z0-9]{1,16}$`)
+
 type Token struct {
-	Name     string `json:"name"`
-	Symbol   string `json:"symbol"`
-	Total    int64  `json:"total"`
-	Decimals int64  `json:"decimals"`
+	Name     string   `json:"name"`
+	Symbol   string   `json:"symbol"`
+	Total    Amount   `json:"total"`
+	Decimals int64    `json:"decimals"`
+	Issuer   HexBytes `json:"issuer,omitempty"`
+
+	Cap       *Amount `json:"cap,omitempty"` // 发行人最多可以增发到的总量, 为空时不能增发
+	Burnable  bool    `json:"burnable,omitempty"`
+	Freezable bool    `json:"freezable,omitempty"`
+
+	TokenMetadata
 }
 
+// Token的展示信息, 发行人可以修改
+type TokenMetadata struct {
+	Description string `json:"description,omitempty"`
+	Website     string `json:"website,omitempty"`
+	Logo        string `json:"logo,omitempty"`
+}
+
+// Token在TokenTree中的地址, 在发行时计算一次, 之后作为TokenTree的key和Balances的key使用
 func (token Token) Address() []byte {
 	v, err := json.Marshal(token)
 	if err != nil {
@@ -25,3 +49,36 @@ func (event Token) Bytes() []byte {
 	data, _ := json.Marshal(event)
 	return data
 }
+
+func (token Token) Valid() bool {
+	if token.Name == "" || token.Total.Sign() <= 0 || !token.Total.Valid() || token.Decimals < 0 || token.Decimals > MaxTokenDecimals {
+		return false
+	}
+	if token.Cap != nil && (token.Cap.Cmp(token.Total) < 0 || !token.Cap.Valid()) {
+		return false
+	}
+	if !tokenSymbolRegexp.MatchString(token.Symbol) || IsReservedSymbol(token.Symbol) {
+		return false
+	}
+	return true
+}
+
+func (token Token) IsIssuer(address []byte) bool {
+	return bytes.Equal(token.Issuer, address)
+}
+
+// EKT和GAS是系统Token，不能被发行
+func IsReservedSymbol(symbol string) bool {
+	symbol = strings.ToUpper(symbol)
+	return symbol == "EKT" || symbol == "GAS"
+}
+
+// Symbol在TokenTree中的索引key, 大小写不同的Symbol视为同一个
+func TokenSymbolKey(symbol string) []byte {
+	return crypto.Sha3_256([]byte("symbol:" + strings.ToUpper(symbol)))
+}
+
+// 被冻结的持有人在TokenTree中的key
+func TokenFrozenKey(tokenAddress string, holder []byte) []byte {
+	return crypto.Sha3_256(append([]byte("frozen:"+tokenAddress), holder...))
+}
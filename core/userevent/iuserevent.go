@@ -2,24 +2,116 @@ package userevent
 
 import (
 	"bytes"
+	"encoding/hex"
+	"errors"
 
 	"github.com/EducationEKT/EKT/core/types"
 	"github.com/EducationEKT/EKT/crypto"
 )
 
-func ValidateTransaction(transaction Transaction) bool {
+// 发送方地址(hex)对应的轮换之后的公钥hash, 由调用方预先从账户状态中读取
+type AuthKeys map[string][][]byte
+
+func (keys AuthKeys) Add(from, authKey []byte) {
+	address := hex.EncodeToString(from)
+	keys[address] = append(keys[address], authKey)
+}
+
+// 签名人等于发送方地址或者预先读取的公钥hash
+func (keys AuthKeys) Allowed(from, signer []byte) bool {
+	if bytes.Equal(from, signer) {
+		return true
+	}
+	for _, authKey := range keys[hex.EncodeToString(from)] {
+		if bytes.Equal(authKey, signer) {
+			return true
+		}
+	}
+	return false
+}
+
+// 校验交易的签名, 校验结果由DefaultVerifier缓存
+func ValidateTransaction(transaction Transaction, authKeys AuthKeys) bool {
+	return DefaultVerifier.Verify(transaction, authKeys)
+}
+
+func validateTransaction(transaction Transaction, authKeys AuthKeys) bool {
 	if bytes.Equal(transaction.GetFrom(), transaction.GetTo()) {
 		return false
 	}
-	if transaction.Amount < 0 {
+	if transaction.Amount.Sign() < 0 || !transaction.Amount.Valid() {
 		return false
 	}
+	if transaction.IsMultiSig() {
+		// 多签交易只校验签名本身，签名人是否满足账户的阈值要求需要根据账户状态判断
+		if len(transaction.GetSign()) != 0 {
+			return false
+		}
+		_, err := Signers(transaction)
+		return err == nil
+	}
+	// 签名人需要是发送方或者预先读取的轮换公钥, 是否等于执行到这笔交易时账户的公钥hash需要根据账户状态判断
+	signer, err := Signer(transaction)
+	return err == nil && authKeys.Allowed(transaction.GetFrom(), signer)
+}
+
+// 返回单签交易签名人的公钥hash
+func Signer(transaction Transaction) ([]byte, error) {
 	pubKey, err := crypto.RecoverPubKey(transaction.Msg(), transaction.GetSign())
 	if err != nil {
+		return nil, err
+	}
+	return types.FromPubKeyToAddress(pubKey), nil
+}
+
+// 返回多签交易所有签名人的地址, 同一个签名人签名多次视为无效交易
+func Signers(transaction Transaction) ([][]byte, error) {
+	if len(transaction.Signs) > types.MaxMultiSigMembers {
+		return nil, errors.New("too many signatures")
+	}
+	signers := make([][]byte, 0, len(transaction.Signs))
+	for _, sign := range transaction.Signs {
+		pubKey, err := crypto.RecoverPubKey(transaction.Msg(), sign)
+		if err != nil {
+			return nil, err
+		}
+		signer := types.FromPubKeyToAddress(pubKey)
+		for _, s := range signers {
+			if bytes.Equal(s, signer) {
+				return nil, errors.New("duplicate signer")
+			}
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+// 根据发送方账户的状态校验交易的授权
+// 普通账户只能使用单个签名，签名人需要等于账户当前的公钥hash，多签账户需要至少Threshold个成员签名
+func CheckAuthorization(transaction Transaction, account *types.Account) bool {
+	if account == nil {
+		account = types.NewAccount(transaction.GetFrom())
+	}
+	if account.MultiSig == nil {
+		if transaction.IsMultiSig() {
+			return false
+		}
+		signer, err := Signer(transaction)
+		return err == nil && bytes.Equal(signer, account.AuthorizedKey())
+	}
+	if !transaction.IsMultiSig() {
+		return false
+	}
+	signers, err := Signers(transaction)
+	if err != nil || len(signers) < account.MultiSig.Threshold {
 		return false
 	}
-	result := bytes.Equal(types.FromPubKeyToAddress(pubKey), transaction.GetFrom())
-	return result
+	for _, signer := range signers {
+		if !account.MultiSig.IsMember(signer) {
+			return false
+		}
+	}
+	return true
 }
 
 func SignTransaction(transaction *Transaction, privKey []byte) error {
@@ -30,3 +122,13 @@ func SignTransaction(transaction *Transaction, privKey []byte) error {
 	transaction.SetSign(sign)
 	return nil
 }
+
+// 多签账户的成员对交易签名, 签名追加到交易的Signs中
+func MultiSignTransaction(transaction *Transaction, privKey []byte) error {
+	sign, err := crypto.Crypto(transaction.Msg(), privKey)
+	if err != nil {
+		return err
+	}
+	transaction.AddSign(sign)
+	return nil
+}
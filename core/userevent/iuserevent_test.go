@@ -0,0 +1,71 @@
+package userevent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/crypto"
+)
+
+func TestCheckAuthorization_MultiSig(t *testing.T) {
+	members := make([][]byte, 0)
+	privKeys := make([][]byte, 0)
+	for i := 0; i < 3; i++ {
+		pubKey, privKey := crypto.GenerateKeyPair()
+		members = append(members, types.FromPubKeyToAddress(pubKey))
+		privKeys = append(privKeys, privKey)
+	}
+	multiSig := types.NewMultiSig(members, 2)
+	if !multiSig.Valid() {
+		t.Fatal("invalid multisig")
+	}
+	account := types.NewAccount(multiSig.Address())
+	account.MultiSig = multiSig
+
+	tx := NewTransaction(account.Address, crypto.Sha3_256([]byte("to")), time.Now().UnixNano()/1e6, 100, 0, 1, "", "")
+	if err := MultiSignTransaction(tx, privKeys[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !validateTransaction(*tx, nil) || CheckAuthorization(*tx, account) {
+		t.Fatal("one signature should not reach the threshold")
+	}
+	MultiSignTransaction(tx, privKeys[0])
+	if validateTransaction(*tx, nil) {
+		t.Fatal("duplicate signer accepted")
+	}
+	tx.Signs = tx.Signs[:1]
+	MultiSignTransaction(tx, privKeys[2])
+	if !validateTransaction(*tx, nil) || !CheckAuthorization(*tx, account) {
+		t.Fatal("valid multisig transaction rejected")
+	}
+	if CheckAuthorization(*tx, types.NewAccount(account.Address)) {
+		t.Fatal("multisig transaction accepted for normal account")
+	}
+}
+
+func TestCheckAuthorization_RotatedKey(t *testing.T) {
+	pubKey, privKey := crypto.GenerateKeyPair()
+	newPubKey, newPrivKey := crypto.GenerateKeyPair()
+	account := types.NewAccount(types.FromPubKeyToAddress(pubKey))
+
+	tx := NewTransaction(account.Address, crypto.Sha3_256([]byte("to")), time.Now().UnixNano()/1e6, 100, 0, 1, "", "")
+	SignTransaction(tx, privKey)
+	if !validateTransaction(*tx, nil) || !CheckAuthorization(*tx, account) {
+		t.Fatal("transaction signed by original key rejected")
+	}
+
+	account.AuthKey = types.FromPubKeyToAddress(newPubKey)
+	if CheckAuthorization(*tx, account) {
+		t.Fatal("transaction signed by rotated key accepted")
+	}
+	SignTransaction(tx, newPrivKey)
+	if validateTransaction(*tx, nil) {
+		t.Fatal("transaction signed by unknown key accepted")
+	}
+	authKeys := make(AuthKeys)
+	authKeys.Add(account.Address, account.AuthKey)
+	if !validateTransaction(*tx, authKeys) || !CheckAuthorization(*tx, account) {
+		t.Fatal("transaction signed by new key rejected")
+	}
+}
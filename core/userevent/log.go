@@ -0,0 +1,15 @@
+package userevent
+
+import (
+	"encoding/json"
+
+	"github.com/EducationEKT/EKT/core/types"
+)
+
+// 合约通过AWM.emit(name, data)记录的事件日志, 保存在交易回执中, 随回执计入ReceiptRoot
+type ContractLog struct {
+	Address types.HexBytes  `json:"address"` // 记录事件的合约地址
+	Name    string          `json:"name"`
+	Index   int             `json:"index"` // 事件在回执中的序号
+	Data    json.RawMessage `json:"data"`
+}
@@ -9,6 +9,12 @@ import (
 
 	"github.com/EducationEKT/EKT/core/types"
 	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/param"
+)
+
+const (
+	// ValidAfter和ValidBefore小于此值时表示区块高度，否则表示毫秒时间戳
+	ValidityTimestampThreshold = 1e12
 )
 
 const (
@@ -28,15 +34,18 @@ type Transactions []Transaction
 type Receipts []TransactionReceipt
 
 type Transaction struct {
-	From         types.HexBytes `json:"from"`
-	To           types.HexBytes `json:"to"`
-	TimeStamp    int64          `json:"time"` // UnixTimeStamp
-	Amount       int64          `json:"amount"`
-	Fee          int64          `json:"fee"`
-	Nonce        int64          `json:"nonce"`
-	Data         string         `json:"data"`
-	TokenAddress string         `json:"tokenAddress"`
-	Sign         types.HexBytes `json:"sign"`
+	From         types.HexBytes   `json:"from"`
+	To           types.HexBytes   `json:"to"`
+	TimeStamp    int64            `json:"time"` // UnixTimeStamp
+	Amount       types.Amount     `json:"amount"`
+	Fee          int64            `json:"fee"`
+	Nonce        int64            `json:"nonce"`
+	Data         string           `json:"data"`
+	TokenAddress string           `json:"tokenAddress"`
+	ValidAfter   int64            `json:"validAfter,omitempty"`  // 交易生效的区块高度或时间戳，参考ValidityTimestampThreshold
+	ValidBefore  int64            `json:"validBefore,omitempty"` // 交易过期的区块高度或时间戳，参考ValidityTimestampThreshold
+	Sign         types.HexBytes   `json:"sign"`
+	Signs        []types.HexBytes `json:"signs,omitempty"` // 多签账户的交易由多个成员分别签名
 
 	Additional string `json:"additional"`
 }
@@ -87,12 +96,12 @@ type SubTransaction struct {
 	Parent       types.HexBytes `json:"parent"`
 	From         types.HexBytes `json:"from"`
 	To           types.HexBytes `json:"to"`
-	Amount       int64          `json:"amount"`
+	Amount       types.Amount   `json:"amount"`
 	Data         string         `json:"data"`
 	TokenAddress string         `json:"tokenAddress"`
 }
 
-func NewSubTransaction(parent, from, to []byte, amount int64, data string, tokenAddress string) *SubTransaction {
+func NewSubTransaction(parent, from, to []byte, amount types.Amount, data string, tokenAddress string) *SubTransaction {
 	return &SubTransaction{
 		Parent:       parent,
 		From:         from,
@@ -111,6 +120,7 @@ type TransactionReceipt struct {
 	Success         bool            `json:"success"`
 	SubTransactions SubTransactions `json:"subTransactions"`
 	FailType        int             `json:"failType"`
+	Logs            []ContractLog   `json:"logs,omitempty"` // 合约执行成功时记录的事件日志
 }
 
 func NewTransaction(from, to []byte, timestamp, amount, fee, nonce int64, data, tokenAddress string) *Transaction {
@@ -118,7 +128,7 @@ func NewTransaction(from, to []byte, timestamp, amount, fee, nonce int64, data,
 		From:         from,
 		To:           to,
 		TimeStamp:    timestamp,
-		Amount:       amount,
+		Amount:       types.NewAmount(amount),
 		Fee:          fee,
 		Nonce:        nonce,
 		Data:         data,
@@ -165,6 +175,14 @@ func (tx Transaction) Msg() []byte {
 	return crypto.Sha3_256([]byte(tx.String()))
 }
 
+func (tx *Transaction) AddSign(sign []byte) {
+	tx.Signs = append(tx.Signs, sign)
+}
+
+func (tx Transaction) IsMultiSig() bool {
+	return len(tx.Signs) > 0
+}
+
 func (tx Transaction) GetFrom() []byte {
 	return tx.From
 }
@@ -230,8 +248,41 @@ func (tx *Transaction) TxId() []byte {
 }
 
 func (tx *Transaction) String() string {
-	return fmt.Sprintf(`{"from": "%s", "to": "%s", "time": %d, "amount": %d, "fee": %d, "nonce": %d, "data": "%s", "tokenAddress": "%s"}`,
-		hex.EncodeToString(tx.From), hex.EncodeToString(tx.To), tx.TimeStamp, tx.Amount, tx.Fee, tx.Nonce, tx.Data, tx.TokenAddress)
+	if tx.ValidAfter == 0 && tx.ValidBefore == 0 {
+		return fmt.Sprintf(`{"from": "%s", "to": "%s", "time": %d, "amount": %s, "fee": %d, "nonce": %d, "data": "%s", "tokenAddress": "%s"}`,
+			hex.EncodeToString(tx.From), hex.EncodeToString(tx.To), tx.TimeStamp, tx.Amount.String(), tx.Fee, tx.Nonce, tx.Data, tx.TokenAddress)
+	}
+	return fmt.Sprintf(`{"from": "%s", "to": "%s", "time": %d, "amount": %s, "fee": %d, "nonce": %d, "data": "%s", "tokenAddress": "%s", "validAfter": %d, "validBefore": %d}`,
+		hex.EncodeToString(tx.From), hex.EncodeToString(tx.To), tx.TimeStamp, tx.Amount.String(), tx.Fee, tx.Nonce, tx.Data, tx.TokenAddress, tx.ValidAfter, tx.ValidBefore)
+}
+
+// 交易在指定区块高度和时间戳上是否已经生效
+func (tx Transaction) Mature(height, timestamp int64) bool {
+	return tx.ValidAfter == 0 || validityValue(tx.ValidAfter, height, timestamp) >= tx.ValidAfter
+}
+
+// 交易在指定区块高度和时间戳上是否已经过期
+func (tx Transaction) Expired(height, timestamp int64) bool {
+	return tx.ValidBefore != 0 && validityValue(tx.ValidBefore, height, timestamp) >= tx.ValidBefore
+}
+
+func (tx Transaction) ValidAt(height, timestamp int64) bool {
+	return tx.Mature(height, timestamp) && !tx.Expired(height, timestamp)
+}
+
+// 有效期和多签名在FORK_TX_EXTENSION激活之前的区块中不能使用
+func (tx Transaction) Supported(height int64) bool {
+	if param.IsActive(param.FORK_TX_EXTENSION, height) {
+		return true
+	}
+	return tx.ValidAfter == 0 && tx.ValidBefore == 0 && len(tx.Signs) == 0
+}
+
+func validityValue(bound, height, timestamp int64) int64 {
+	if bound < ValidityTimestampThreshold {
+		return height
+	}
+	return timestamp
 }
 
 func (tx Transaction) Bytes() []byte {
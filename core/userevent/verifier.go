@@ -0,0 +1,106 @@
+package userevent
+
+import (
+	"runtime"
+	"sync"
+)
+
+const (
+	DefaultVerifiedCacheSize = 100000
+)
+
+// 默认的签名校验器，API和区块校验共用同一个缓存
+var DefaultVerifier = NewVerifier(runtime.NumCPU(), DefaultVerifiedCacheSize)
+
+// Verifier使用固定数量的worker并行校验交易签名，并缓存校验通过的交易id
+// 交易id是对包含签名在内的整个交易计算的hash，所以缓存命中即表示同一个交易的签名已经校验过
+type Verifier struct {
+	workers   int
+	cacheSize int
+
+	locker   sync.RWMutex
+	verified map[string]bool
+	order    []string
+}
+
+func NewVerifier(workers, cacheSize int) *Verifier {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Verifier{
+		workers:   workers,
+		cacheSize: cacheSize,
+		verified:  make(map[string]bool),
+		order:     make([]string, 0),
+	}
+}
+
+// authKeys为发送方轮换之后的公钥hash, 单签交易的签名人需要是发送方或者其中之一
+func (verifier *Verifier) Verify(tx Transaction, authKeys AuthKeys) bool {
+	txId := tx.TransactionId()
+	if verifier.IsVerified(txId) {
+		return true
+	}
+	if !validateTransaction(tx, authKeys) {
+		return false
+	}
+	verifier.markVerified(txId)
+	return true
+}
+
+// 并行校验一组交易，返回每个交易的校验结果
+func (verifier *Verifier) VerifyAll(txs []Transaction, authKeys AuthKeys) []bool {
+	results := make([]bool, len(txs))
+	jobs := make(chan int, len(txs))
+	for i := range txs {
+		jobs <- i
+	}
+	close(jobs)
+
+	workers := verifier.workers
+	if len(txs) < workers {
+		workers = len(txs)
+	}
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results[index] = verifier.Verify(txs[index], authKeys)
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// 并行校验区块中的所有交易，全部通过才返回true
+func (verifier *Verifier) VerifyBatch(txs []Transaction, authKeys AuthKeys) bool {
+	for _, result := range verifier.VerifyAll(txs, authKeys) {
+		if !result {
+			return false
+		}
+	}
+	return true
+}
+
+func (verifier *Verifier) IsVerified(txId string) bool {
+	verifier.locker.RLock()
+	defer verifier.locker.RUnlock()
+	return verifier.verified[txId]
+}
+
+func (verifier *Verifier) markVerified(txId string) {
+	verifier.locker.Lock()
+	defer verifier.locker.Unlock()
+	if verifier.verified[txId] {
+		return
+	}
+	verifier.verified[txId] = true
+	verifier.order = append(verifier.order, txId)
+	if len(verifier.order) > verifier.cacheSize {
+		delete(verifier.verified, verifier.order[0])
+		verifier.order = verifier.order[1:]
+	}
+}
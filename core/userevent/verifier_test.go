@@ -0,0 +1,37 @@
+package userevent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/crypto"
+)
+
+func TestVerifier_VerifyBatch(t *testing.T) {
+	pubKey, privKey := crypto.GenerateKeyPair()
+	from := types.FromPubKeyToAddress(pubKey)
+	to := crypto.Sha3_256([]byte("to"))
+
+	txs := make([]Transaction, 0)
+	for nonce := int64(1); nonce <= 10; nonce++ {
+		tx := NewTransaction(from, to, time.Now().UnixNano()/1e6, 100, 0, nonce, "", "")
+		if err := SignTransaction(tx, privKey); err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, *tx)
+	}
+
+	verifier := NewVerifier(4, 100)
+	if !verifier.VerifyBatch(txs, nil) {
+		t.Fatal("valid transactions rejected")
+	}
+	if !verifier.IsVerified(txs[0].TransactionId()) {
+		t.Fatal("verified transaction is not cached")
+	}
+
+	txs[5].Amount = types.NewAmount(200)
+	if verifier.VerifyBatch(txs, nil) {
+		t.Fatal("tampered transaction accepted")
+	}
+}
@@ -0,0 +1,185 @@
+package crosschain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/EducationEKT/EKT/blockchain"
+	"github.com/EducationEKT/EKT/conf"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/ektclient"
+	"github.com/EducationEKT/EKT/encapdb"
+	"github.com/EducationEKT/EKT/log"
+	"github.com/EducationEKT/EKT/param"
+)
+
+// 区块中一笔发往外部公链的跨链交易
+type Outgoing struct {
+	PublicChainId string                       `json:"publicChainId"` // 目标公链
+	Height        int64                        `json:"height"`
+	Message       blockchain.CrossChainMessage `json:"message"`
+}
+
+// 跨链交易在发送方链上的交易id
+func (outgoing Outgoing) TxId() []byte {
+	var tx userevent.Transaction
+	if json.Unmarshal(outgoing.Message.Tx, &tx) != nil {
+		return nil
+	}
+	return tx.TxId()
+}
+
+// 从区块的交易和回执中找出执行成功的跨链发送, 生成提交给目标链的消息
+func OutgoingMessages(header blockchain.Header, votes blockchain.Votes) ([]Outgoing, error) {
+	outgoings := make([]Outgoing, 0)
+	if header.TxRoot == nil || header.ReceiptRoot == nil {
+		return outgoings, nil
+	}
+	txIds := make([][]byte, 0)
+	targets := make([]string, 0)
+	err := header.TxRoot.Iterate(func(key, value []byte) bool {
+		var tx userevent.Transaction
+		if json.Unmarshal(value, &tx) != nil {
+			return true
+		}
+		op, ok := blockchain.CrossChainSendOf(tx)
+		if !ok {
+			return true
+		}
+		var receipt userevent.TransactionReceipt
+		if err := header.ReceiptRoot.GetInterfaceValue(key, &receipt); err != nil || !receipt.Success {
+			return true
+		}
+		txIds = append(txIds, key)
+		targets = append(targets, op.PublicChainId)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, txId := range txIds {
+		message, err := blockchain.NewCrossChainMessage(param.PublicChainId(), header, votes, txId)
+		if err != nil {
+			return nil, err
+		}
+		outgoings = append(outgoings, Outgoing{PublicChainId: targets[i], Height: header.Height, Message: *message})
+	}
+	return outgoings, nil
+}
+
+// 跨链中继, 扫描链上的跨链发送并提交给目标链, 没有收到目标链的到账确认时每个出块间隔重试一次
+type Relay struct {
+	chain   *blockchain.BlockChain
+	height  int64
+	pending map[string]Outgoing
+	locker  sync.Mutex
+}
+
+func NewRelay(chain *blockchain.BlockChain) *Relay {
+	return &Relay{
+		chain:   chain,
+		height:  encapdb.GetRelayHeight(chain.ChainId),
+		pending: make(map[string]Outgoing),
+	}
+}
+
+func (relay *Relay) Start() {
+	for {
+		relay.scan()
+		relay.deliver()
+		time.Sleep(relay.chain.ChainParams().Interval())
+	}
+}
+
+// 没有确认的跨链消息
+func (relay *Relay) Pending() []Outgoing {
+	relay.locker.Lock()
+	defer relay.locker.Unlock()
+	list := make([]Outgoing, 0, len(relay.pending))
+	for _, outgoing := range relay.pending {
+		list = append(list, outgoing)
+	}
+	return list
+}
+
+func (relay *Relay) scan() {
+	for relay.height < relay.chain.GetLastHeight() {
+		header := encapdb.GetHeaderByHeight(relay.chain.ChainId, relay.height+1)
+		if header == nil {
+			return
+		}
+		// 区块的投票结果保存之后才能生成消息
+		votes := encapdb.GetVoteResults(relay.chain.ChainId, hex.EncodeToString(header.CalculateHash()))
+		if len(votes) == 0 {
+			return
+		}
+		outgoings, err := OutgoingMessages(*header, votes)
+		if err != nil {
+			log.Info("crosschain relay: scan block %d failed, %v", header.Height, err)
+			return
+		}
+		relay.locker.Lock()
+		for _, outgoing := range outgoings {
+			relay.pending[hex.EncodeToString(outgoing.TxId())] = outgoing
+		}
+		relay.height = header.Height
+		relay.locker.Unlock()
+	}
+	relay.saveHeight()
+}
+
+func (relay *Relay) deliver() {
+	for _, outgoing := range relay.Pending() {
+		target := crossChainConf(outgoing.PublicChainId)
+		if target == nil {
+			log.Info("crosschain relay: public chain %s is not configured", outgoing.PublicChainId)
+			continue
+		}
+		chainId := target.ChainId
+		if chainId == 0 {
+			chainId = param.MainChainId
+		}
+		confirmed, err := ektclient.NewChainClient(chainId, target.Peers).SendCrossChainMessage(outgoing.Message)
+		if err != nil {
+			log.Info("crosschain relay: send %s to %s failed, %v", hex.EncodeToString(outgoing.TxId()), outgoing.PublicChainId, err)
+			continue
+		}
+		if confirmed {
+			relay.confirm(outgoing)
+		}
+	}
+}
+
+func (relay *Relay) confirm(outgoing Outgoing) {
+	relay.locker.Lock()
+	delete(relay.pending, hex.EncodeToString(outgoing.TxId()))
+	relay.locker.Unlock()
+	relay.saveHeight()
+}
+
+// 保存的高度之前的跨链消息都已经确认, 重启之后从这个高度继续
+func (relay *Relay) saveHeight() {
+	relay.locker.Lock()
+	height := relay.height
+	for _, outgoing := range relay.pending {
+		if outgoing.Height <= height {
+			height = outgoing.Height - 1
+		}
+	}
+	relay.locker.Unlock()
+	encapdb.SetRelayHeight(relay.chain.ChainId, height)
+}
+
+func crossChainConf(publicChainId string) *conf.CrossChainConf {
+	if conf.EKTConfig == nil {
+		return nil
+	}
+	for i := range conf.EKTConfig.CrossChains {
+		if conf.EKTConfig.CrossChains[i].PublicChainId == publicChainId {
+			return &conf.EKTConfig.CrossChains[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package crosschain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/EducationEKT/EKT/MPTPlus"
+	"github.com/EducationEKT/EKT/blockchain"
+	"github.com/EducationEKT/EKT/conf"
+	"github.com/EducationEKT/EKT/contract"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/db"
+)
+
+// 在本地模拟两条公链: SRC链上的区块包含一笔发往EKT的跨链交易, EKT链用注册的SRC委托人验证中继生成的消息
+func TestRelayMessage(t *testing.T) {
+	db.EktDB = db.NewMemKVDatabase()
+	defer func() { conf.EKTConfig = nil }()
+
+	delegates := make([]types.HexBytes, 0)
+	privKeys := make([][]byte, 0)
+	for i := 0; i < 3; i++ {
+		pub, priv := crypto.GenerateKeyPair()
+		delegates = append(delegates, types.FromPubKeyToAddress(pub))
+		privKeys = append(privKeys, priv)
+	}
+
+	// 发送方公链
+	conf.EKTConfig = &conf.EKTConf{PublicChainId: "SRC"}
+	source := blockchain.Header{
+		Height:      5,
+		TxRoot:      MPTPlus.NewMTP(db.GetDBInst()),
+		ReceiptRoot: MPTPlus.NewMTP(db.GetDBInst()),
+		Version:     blockchain.HEADER_VERSION_EVENT,
+	}
+	to := crypto.Sha3_256([]byte("receiver"))
+	system, _ := hex.DecodeString(contract.SYSTEM_AUTHOR + contract.CROSSCHAIN_CONTRACT)
+	data := fmt.Sprintf(`{"op":"send","publicChainId":"EKT","tokenId":"EKT","to":"%s"}`, hex.EncodeToString(to))
+	send := userevent.NewTransaction(delegates[0], system, 1, 100, 0, 1, data, types.EKTAddress)
+	transfer := userevent.NewTransaction(delegates[0], to, 1, 100, 0, 2, "", types.EKTAddress)
+	for _, tx := range []*userevent.Transaction{send, transfer} {
+		receipt := userevent.NewTransactionReceipt(*tx, true, userevent.FailType_SUCCESS)
+		source.TxRoot.MustInsert(tx.TxId(), tx.Bytes())
+		source.ReceiptRoot.MustInsert(tx.TxId(), receipt.Bytes())
+	}
+	votes := make(blockchain.Votes, 0)
+	for i := 0; i < 2; i++ {
+		vote := blockchain.PeerBlockVote{
+			Vote: blockchain.BlockVoteDetail{BlockchainId: 1, BlockHash: source.CalculateHash(), BlockHeight: source.Height, VoteResult: true},
+			Peer: types.Peer{Account: hex.EncodeToString(delegates[i]), Address: "127.0.0.1", Port: int32(19951 + i)},
+		}
+		if err := vote.Sign(privKeys[i]); err != nil {
+			t.Fatal(err)
+		}
+		votes = append(votes, vote)
+	}
+	outgoings, err := OutgoingMessages(source, votes)
+	if err != nil || len(outgoings) != 1 || outgoings[0].PublicChainId != "EKT" {
+		t.Fatal("outgoing messages mismatch", err, outgoings)
+	}
+	body, _ := json.Marshal(outgoings[0].Message)
+
+	// 目标公链
+	conf.EKTConfig = &conf.EKTConf{PublicChainId: "EKT"}
+	dest := blockchain.Header{Height: 10, ChainStat: MPTPlus.NewMTP(db.GetDBInst())}
+	registration := blockchain.CrossChainRegistration{
+		PublicChainId: "SRC",
+		TokenId:       "EKT",
+		TokenAddress:  types.EKTAddress,
+		OpCodeId:      "0000FFFF00FF",
+		Delegates:     delegates,
+		Status:        blockchain.CROSSCHAIN_STATUS_ACTIVE,
+	}
+	dest.ChainStat.MustInsert(blockchain.CrossChainKey("SRC", types.EKTAddress), registration.Bytes())
+
+	var message blockchain.CrossChainMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatal(err)
+	}
+	result, err := dest.VerifyCrossChainMessage(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PublicChainId != "SRC" || hex.EncodeToString(result.To) != hex.EncodeToString(to) || result.Amount.Int64() != 100 {
+		t.Fatal("transfer mismatch", result)
+	}
+
+	tampered := message
+	forged := *send
+	forged.Amount = types.NewAmount(1000)
+	tampered.Tx = forged.Bytes()
+	if _, err := dest.VerifyCrossChainMessage(tampered); err == nil {
+		t.Fatal("forged transaction accepted")
+	}
+	tampered = message
+	tampered.Votes = message.Votes[:1]
+	if _, err := dest.VerifyCrossChainMessage(tampered); err == nil {
+		t.Fatal("message without majority accepted")
+	}
+}
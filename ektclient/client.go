@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"github.com/EducationEKT/EKT/param"
 	"strconv"
+	"strings"
 
 	"github.com/EducationEKT/EKT/blockchain"
 	"github.com/EducationEKT/EKT/core/types"
@@ -23,16 +24,30 @@ func InitEKTClient() {
 }
 
 type Client struct {
-	peers []types.Peer
+	chainId int64
+	peers   []types.Peer
 }
 
 func NewClient(peers []types.Peer) IClient {
-	return Client{peers: peers}
+	return NewChainClient(param.MainChainId, peers)
+}
+
+// 访问指定链的客户端, 所有请求都带上chainId参数
+func NewChainClient(chainId int64, peers []types.Peer) IClient {
+	return Client{chainId: chainId, peers: peers}
+}
+
+func (client Client) url(peer types.Peer, path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), path, sep, "chainId=", strconv.FormatInt(client.chainId, 10))
 }
 
 func (client Client) GetHeaderByHeight(height int64) *blockchain.Header {
 	for _, peer := range client.peers {
-		url := util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), "/block/api/getHeaderByHeight?height=", strconv.Itoa(int(height)))
+		url := client.url(peer, "/block/api/getHeaderByHeight?height="+strconv.Itoa(int(height)))
 		body, err := util.HttpGet(url)
 		if err != nil {
 			continue
@@ -54,7 +69,7 @@ func (client Client) GetHeaderByHeight(height int64) *blockchain.Header {
 
 func (client Client) GetBlockByHeight(height int64) *blockchain.Block {
 	for _, peer := range client.peers {
-		url := util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), "/block/api/getBlockByHeight?height=", strconv.Itoa(int(height)))
+		url := client.url(peer, "/block/api/getBlockByHeight?height="+strconv.Itoa(int(height)))
 		body, err := util.HttpGet(url)
 		if err != nil {
 			continue
@@ -89,7 +104,7 @@ func (client Client) GetValueByHash(hash []byte) []byte {
 
 func (client Client) GetLastBlock() *blockchain.Header {
 	for _, peer := range client.peers {
-		url := util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), "/block/api/last")
+		url := client.url(peer, "/block/api/last")
 		body, err := util.HttpGet(url)
 		if err != nil {
 			continue
@@ -111,7 +126,7 @@ func (client Client) GetLastBlock() *blockchain.Header {
 
 func (client Client) GetVotesByBlockHash(hash string) blockchain.Votes {
 	for _, peer := range client.peers {
-		url := util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), "/vote/api/getVotes?hash=", hash)
+		url := client.url(peer, "/vote/api/getVotes?hash="+hash)
 		body, err := util.HttpGet(url)
 		if err != nil {
 			continue
@@ -135,7 +150,7 @@ func (client Client) GetVotesByBlockHash(hash string) blockchain.Votes {
 func (client Client) BroadcastBlock(block blockchain.Block) {
 	data := block.Bytes()
 	for _, peer := range client.peers {
-		url := util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), "/block/api/blockFromPeer")
+		url := client.url(peer, "/block/api/blockFromPeer")
 		go util.HttpPost(url, data)
 	}
 }
@@ -143,7 +158,7 @@ func (client Client) BroadcastBlock(block blockchain.Block) {
 func (client Client) SendVote(vote blockchain.PeerBlockVote) {
 	data := vote.Bytes()
 	for _, peer := range client.peers {
-		url := util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), "/vote/api/vote")
+		url := client.url(peer, "/vote/api/vote")
 		go util.HttpPost(url, data)
 	}
 }
@@ -151,14 +166,14 @@ func (client Client) SendVote(vote blockchain.PeerBlockVote) {
 func (client Client) SendVoteResult(votes blockchain.Votes) {
 	data := votes.Bytes()
 	for _, peer := range client.peers {
-		url := util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), "/vote/api/voteResult")
+		url := client.url(peer, "/vote/api/voteResult")
 		go util.HttpPost(url, data)
 	}
 }
 
 func (client Client) GetSuggestionFee() int64 {
 	for _, peer := range client.peers {
-		url := util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), "/transaction/api/fee")
+		url := client.url(peer, "/transaction/api/fee")
 		body, err := util.HttpGet(url)
 		if err != nil {
 			continue
@@ -181,7 +196,7 @@ func (client Client) GetSuggestionFee() int64 {
 
 func (client Client) GetAccountNonce(address string) int64 {
 	for _, peer := range client.peers {
-		url := util.StringJoint("http://", peer.Address, ":", strconv.Itoa(int(peer.Port)), "/account/api/nonce?address=", address)
+		url := client.url(peer, "/account/api/nonce?address="+address)
 		body, err := util.HttpGet(url)
 		if err != nil {
 			continue
@@ -208,7 +223,7 @@ func (client Client) SendTransaction(tx userevent.Transaction) error {
 	data := tx.Bytes()
 
 	for _, node := range client.peers {
-		url := fmt.Sprintf(`http://%s:%d/transaction/api/newTransaction`, node.Address, node.Port)
+		url := client.url(node, "/transaction/api/newTransaction")
 		_, err := util.HttpPost(url, data)
 		if err == nil {
 			return nil
@@ -220,7 +235,7 @@ func (client Client) SendTransaction(tx userevent.Transaction) error {
 
 func (client Client) GetReceipt(txHash string) *userevent.ReceiptDetail {
 	for _, node := range client.peers {
-		url := fmt.Sprintf(`http://%s:%d/transaction/api/getReceiptByTxHash?hash=%s`, node.Address, node.Port, txHash)
+		url := client.url(node, fmt.Sprintf(`/transaction/api/getReceiptByTxHash?hash=%s`, txHash))
 		resp, err := util.HttpGet(url)
 		if err != nil {
 			continue
@@ -242,7 +257,7 @@ func (client Client) GetReceipt(txHash string) *userevent.ReceiptDetail {
 
 func (client Client) GetGenesisAccounts() []types.Account {
 	for _, node := range client.peers {
-		url := fmt.Sprintf(`http://%s:%d/account/api/genesisAccount`, node.Address, node.Port)
+		url := client.url(node, "/account/api/genesisAccount")
 		resp, err := util.HttpGet(url)
 		if err != nil {
 			continue
@@ -261,8 +276,101 @@ func (client Client) GetGenesisAccounts() []types.Account {
 	return nil
 }
 
+func (client Client) GetTokens(offset, limit int) []blockchain.TokenInfo {
+	for _, node := range client.peers {
+		url := client.url(node, fmt.Sprintf(`/token/api/list?offset=%d&limit=%d`, offset, limit))
+		result := struct {
+			Status int                    `json:"status"`
+			Msg    string                 `json:"msg"`
+			Result []blockchain.TokenInfo `json:"result"`
+		}{}
+		if getJSON(url, &result) != nil || result.Status != 0 {
+			continue
+		}
+		return result.Result
+	}
+	return nil
+}
+
+func (client Client) GetToken(address string) *blockchain.TokenInfo {
+	return client.getTokenInfo("address=" + address)
+}
+
+func (client Client) GetTokenBySymbol(symbol string) *blockchain.TokenInfo {
+	return client.getTokenInfo("symbol=" + symbol)
+}
+
+func (client Client) getTokenInfo(query string) *blockchain.TokenInfo {
+	for _, node := range client.peers {
+		url := client.url(node, fmt.Sprintf(`/token/api/info?%s`, query))
+		result := struct {
+			Status int                   `json:"status"`
+			Msg    string                `json:"msg"`
+			Result *blockchain.TokenInfo `json:"result"`
+		}{}
+		if getJSON(url, &result) != nil || result.Status != 0 || result.Result == nil {
+			continue
+		}
+		return result.Result
+	}
+	return nil
+}
+
+func (client Client) GetTokenHolders(address string, offset, limit int) []blockchain.TokenHolder {
+	for _, node := range client.peers {
+		url := client.url(node, fmt.Sprintf(`/token/api/holders?address=%s&offset=%d&limit=%d`, address, offset, limit))
+		result := struct {
+			Status int                      `json:"status"`
+			Msg    string                   `json:"msg"`
+			Result []blockchain.TokenHolder `json:"result"`
+		}{}
+		if getJSON(url, &result) != nil || result.Status != 0 {
+			continue
+		}
+		return result.Result
+	}
+	return nil
+}
+
+func getJSON(url string, v interface{}) error {
+	resp, err := util.HttpGet(url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(resp, v)
+}
+
 func GetVotesFromResp(body []byte) blockchain.Votes {
 	var votes blockchain.Votes
 	json.Unmarshal(body, &votes)
 	return votes
 }
+
+// 把跨链消息提交给目标链, 返回目标链是否已经到账
+func (client Client) SendCrossChainMessage(message blockchain.CrossChainMessage) (bool, error) {
+	data, _ := json.Marshal(message)
+	err := errors.New("no peer")
+	for _, peer := range client.peers {
+		var body []byte
+		body, err = util.HttpPost(client.url(peer, "/crosschain/api/receive"), data)
+		if err != nil {
+			continue
+		}
+		resp := struct {
+			Status int    `json:"status"`
+			Msg    string `json:"msg"`
+			Result struct {
+				Confirmed bool `json:"confirmed"`
+			} `json:"result"`
+		}{}
+		if err = json.Unmarshal(body, &resp); err != nil {
+			continue
+		}
+		if resp.Status != 0 {
+			err = errors.New(resp.Msg)
+			continue
+		}
+		return resp.Result.Confirmed, nil
+	}
+	return false, err
+}
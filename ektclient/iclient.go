@@ -36,5 +36,14 @@ type IClient interface {
 	GetAccountNonce(address string) int64
 	GetGenesisAccounts() []types.Account
 
+	// token
+	GetTokens(offset, limit int) []blockchain.TokenInfo
+	GetToken(address string) *blockchain.TokenInfo
+	GetTokenBySymbol(symbol string) *blockchain.TokenInfo
+	GetTokenHolders(address string, offset, limit int) []blockchain.TokenHolder
+
+	// crosschain
+	SendCrossChainMessage(message blockchain.CrossChainMessage) (bool, error)
+
 	GetValueByHash(hash []byte) []byte
 }
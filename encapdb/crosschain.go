@@ -0,0 +1,24 @@
+package encapdb
+
+import (
+	"strconv"
+
+	"github.com/EducationEKT/EKT/db"
+	"github.com/EducationEKT/EKT/log"
+	"github.com/EducationEKT/EKT/schema"
+)
+
+// 跨链中继已经处理完的区块高度, 重启之后从这个高度继续扫描
+func GetRelayHeight(chainId int64) int64 {
+	data, err := db.GetDBInst().Get(schema.CrossChainRelayHeightKey(chainId))
+	if err != nil {
+		return 0
+	}
+	height, _ := strconv.ParseInt(string(data), 10, 64)
+	return height
+}
+
+func SetRelayHeight(chainId, height int64) {
+	key := schema.CrossChainRelayHeightKey(chainId)
+	log.LogErr(db.GetDBInst().Set(key, []byte(strconv.FormatInt(height, 10))))
+}
@@ -0,0 +1,253 @@
+package gossip
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/EducationEKT/EKT/conf"
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/log"
+	"github.com/EducationEKT/EKT/param"
+	"github.com/EducationEKT/EKT/util"
+)
+
+const (
+	// 最多记录的已见过的交易hash数量，超过之后淘汰最早的记录
+	MaxSeenTx = 100000
+	// 最多记录的通过交易通知发现的节点数量
+	MaxGossipPeers = 64
+	// 超过这个时间没有再发来通知的节点不再广播
+	GossipPeerExpire = 10 * time.Minute
+)
+
+var inst *TxGossip
+
+func init() {
+	inst = NewTxGossip()
+}
+
+func GetInst() *TxGossip {
+	return inst
+}
+
+// 节点之间广播的交易通知，只包含交易hash，收到通知的节点根据hash从Peer拉取交易
+type TxAnnouncement struct {
+	ChainId int64            `json:"chainId,omitempty"`
+	Peer    types.Peer       `json:"peer"`
+	Hashes  []types.HexBytes `json:"hashes"`
+}
+
+// 没有chainId的通知来自旧版本的节点，属于主链
+func (announcement TxAnnouncement) GetChainId() int64 {
+	if announcement.ChainId == 0 {
+		return param.MainChainId
+	}
+	return announcement.ChainId
+}
+
+func (announcement TxAnnouncement) Bytes() []byte {
+	data, _ := json.Marshal(announcement)
+	return data
+}
+
+type TxGossip struct {
+	peerLocker sync.Mutex
+	peers      map[string]*gossipPeer
+	verifying  map[string]bool
+	alive      func(peer types.Peer) bool
+
+	locker sync.Mutex
+	seen   map[string]uint64 // 交易hash -> 标记时的序号
+	order  []seenTx
+	seq    uint64
+}
+
+// 通过交易通知发现的节点和最后一次收到它的通知的时间
+type gossipPeer struct {
+	peer     types.Peer
+	lastSeen time.Time
+}
+
+// 按照标记顺序记录的交易hash, 淘汰时只有序号相同才删除, 取消标记之后重新标记的记录不会被旧的记录淘汰
+type seenTx struct {
+	key string
+	seq uint64
+}
+
+func NewTxGossip() *TxGossip {
+	return &TxGossip{
+		peers:     make(map[string]*gossipPeer),
+		verifying: make(map[string]bool),
+		alive:     types.Peer.IsAlive,
+		seen:      make(map[string]uint64),
+		order:     make([]seenTx, 0),
+	}
+}
+
+// 记录一个通过交易通知发现的节点，之后的交易通知也会发送给这个节点
+// 通知中的节点没有经过认证, 新节点需要能够ping通才会记录, 记录的数量不超过MaxGossipPeers, 已经记录的节点刷新最后通知时间
+func (gossip *TxGossip) AddPeer(peer types.Peer) {
+	if peer.Address == "" || peer.Port == 0 || gossip.isSelf(peer) || gossip.isKnown(peer) {
+		return
+	}
+	key := peer.String()
+	gossip.peerLocker.Lock()
+	defer gossip.peerLocker.Unlock()
+	if p, exist := gossip.peers[key]; exist {
+		p.lastSeen = time.Now()
+		return
+	}
+	gossip.expirePeers()
+	if gossip.verifying[key] || len(gossip.peers)+len(gossip.verifying) >= MaxGossipPeers {
+		return
+	}
+	gossip.verifying[key] = true
+	go gossip.verifyPeer(key, peer)
+}
+
+func (gossip *TxGossip) verifyPeer(key string, peer types.Peer) {
+	alive := gossip.alive(peer)
+	gossip.peerLocker.Lock()
+	defer gossip.peerLocker.Unlock()
+	delete(gossip.verifying, key)
+	if alive && len(gossip.peers) < MaxGossipPeers {
+		gossip.peers[key] = &gossipPeer{peer: peer, lastSeen: time.Now()}
+	}
+}
+
+// 删除超过GossipPeerExpire没有发来通知的节点, 调用方需要持有peerLocker
+func (gossip *TxGossip) expirePeers() {
+	for key, p := range gossip.peers {
+		if time.Since(p.lastSeen) > GossipPeerExpire {
+			delete(gossip.peers, key)
+		}
+	}
+}
+
+// 委托人节点和配置文件中的节点, 总是会收到交易通知, 不需要记录
+func (gossip *TxGossip) isKnown(peer types.Peer) bool {
+	for _, chainId := range param.ChainIds() {
+		for _, delegate := range param.ChainDelegates(chainId) {
+			if delegate.Equal(peer) {
+				return true
+			}
+		}
+	}
+	if conf.EKTConfig != nil {
+		for _, p := range conf.EKTConfig.Peers {
+			if p.Equal(peer) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 交易通知的目标节点：指定链的委托人节点、配置文件中的节点以及通过交易通知发现的节点
+func (gossip *TxGossip) Peers(chainId int64) []types.Peer {
+	peers := make([]types.Peer, 0)
+	add := func(peer types.Peer) {
+		if gossip.isSelf(peer) {
+			return
+		}
+		for _, p := range peers {
+			if p.Equal(peer) {
+				return
+			}
+		}
+		peers = append(peers, peer)
+	}
+	for _, peer := range param.ChainDelegates(chainId) {
+		add(peer)
+	}
+	if conf.EKTConfig != nil {
+		for _, peer := range conf.EKTConfig.Peers {
+			add(peer)
+		}
+	}
+	gossip.peerLocker.Lock()
+	gossip.expirePeers()
+	for _, p := range gossip.peers {
+		add(p.peer)
+	}
+	gossip.peerLocker.Unlock()
+	return peers
+}
+
+// 标记一个交易hash已经处理过, 如果之前已经标记过返回false
+func (gossip *TxGossip) MarkSeen(hash []byte) bool {
+	key := hex.EncodeToString(hash)
+	gossip.locker.Lock()
+	defer gossip.locker.Unlock()
+	if _, exist := gossip.seen[key]; exist {
+		return false
+	}
+	gossip.seq++
+	gossip.seen[key] = gossip.seq
+	gossip.order = append(gossip.order, seenTx{key: key, seq: gossip.seq})
+	if len(gossip.order) > MaxSeenTx {
+		if oldest := gossip.order[0]; gossip.seen[oldest.key] == oldest.seq {
+			delete(gossip.seen, oldest.key)
+		}
+		gossip.order = gossip.order[1:]
+	}
+	return true
+}
+
+// 拉取交易失败时取消标记，以便从其他节点的通知中重新拉取
+func (gossip *TxGossip) Forget(hash []byte) {
+	gossip.locker.Lock()
+	defer gossip.locker.Unlock()
+	delete(gossip.seen, hex.EncodeToString(hash))
+}
+
+// 将主链的交易hash通知给所有已知节点
+func (gossip *TxGossip) Announce(hashes ...[]byte) {
+	gossip.AnnounceTo(param.MainChainId, hashes...)
+}
+
+// 将指定链的交易hash通知给所有已知节点
+func (gossip *TxGossip) AnnounceTo(chainId int64, hashes ...[]byte) {
+	if len(hashes) == 0 {
+		return
+	}
+	announcement := TxAnnouncement{ChainId: chainId, Hashes: make([]types.HexBytes, 0, len(hashes))}
+	if conf.EKTConfig != nil {
+		announcement.Peer = conf.EKTConfig.Node
+	}
+	for _, hash := range hashes {
+		gossip.MarkSeen(hash)
+		announcement.Hashes = append(announcement.Hashes, hash)
+	}
+	data := announcement.Bytes()
+	for _, peer := range gossip.Peers(chainId) {
+		url := fmt.Sprintf(`http://%s:%d/transaction/api/announce`, peer.Address, peer.Port)
+		go func(url string) {
+			_, err := util.HttpPost(url, data)
+			log.LogErr(err)
+		}(url)
+	}
+}
+
+// 根据交易hash从指定节点拉取交易，交易以自己的hash为key存储在db中
+func (gossip *TxGossip) Fetch(peer types.Peer, hash []byte) *userevent.Transaction {
+	data, err := peer.GetDBValue(hex.EncodeToString(hash))
+	if err != nil || !bytes.Equal(crypto.Sha3_256(data), hash) {
+		return nil
+	}
+	var tx userevent.Transaction
+	if err = json.Unmarshal(data, &tx); err != nil || !bytes.Equal(tx.TxId(), hash) {
+		return nil
+	}
+	return &tx
+}
+
+func (gossip *TxGossip) isSelf(peer types.Peer) bool {
+	return conf.EKTConfig != nil && peer.Equal(conf.EKTConfig.Node)
+}
@@ -0,0 +1,77 @@
+package gossip
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/param"
+)
+
+func TestTxGossip_MarkSeen(t *testing.T) {
+	gossip := NewTxGossip()
+	hash := crypto.Sha3_256([]byte("tx"))
+	if !gossip.MarkSeen(hash) {
+		t.Fatal("first mark should succeed")
+	}
+	if gossip.MarkSeen(hash) {
+		t.Fatal("duplicated hash should be ignored")
+	}
+	gossip.Forget(hash)
+	if !gossip.MarkSeen(hash) {
+		t.Fatal("forgotten hash should be marked again")
+	}
+}
+
+// 取消标记之后重新标记的hash不能被旧的记录淘汰
+func TestTxGossip_ForgetEviction(t *testing.T) {
+	gossip := NewTxGossip()
+	hash := crypto.Sha3_256([]byte("tx"))
+	gossip.MarkSeen(hash)
+	gossip.Forget(hash)
+	gossip.MarkSeen(hash)
+	for i := 0; i < MaxSeenTx-1; i++ {
+		gossip.MarkSeen([]byte(strconv.Itoa(i)))
+	}
+	if gossip.MarkSeen(hash) {
+		t.Fatal("remarked hash evicted by stale record")
+	}
+}
+
+// 通知中的节点需要ping通才会记录, 数量有上限, 长时间没有通知的节点过期
+func TestTxGossip_AddPeer(t *testing.T) {
+	gossip := NewTxGossip()
+	gossip.alive = func(peer types.Peer) bool { return peer.Port%2 == 0 }
+	for i := 0; i < 2*MaxGossipPeers+10; i++ {
+		gossip.AddPeer(types.Peer{Address: "10.0.0.1", Port: int32(20000 + i)})
+		for gossip.pending() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	peers := gossip.Peers(param.MainChainId)
+	if len(peers) != MaxGossipPeers {
+		t.Fatal("peer count mismatch", len(peers))
+	}
+	for _, peer := range peers {
+		if peer.Port%2 != 0 {
+			t.Fatal("unreachable peer recorded", peer)
+		}
+	}
+
+	gossip.peerLocker.Lock()
+	for _, p := range gossip.peers {
+		p.lastSeen = time.Now().Add(-2 * GossipPeerExpire)
+	}
+	gossip.peerLocker.Unlock()
+	if len(gossip.Peers(param.MainChainId)) != 0 {
+		t.Fatal("stale peers should expire")
+	}
+}
+
+func (gossip *TxGossip) pending() int {
+	gossip.peerLocker.Lock()
+	defer gossip.peerLocker.Unlock()
+	return len(gossip.verifying)
+}
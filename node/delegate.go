@@ -12,6 +12,7 @@ import (
 	"github.com/EducationEKT/EKT/ektclient"
 	"github.com/EducationEKT/EKT/encapdb"
 	"github.com/EducationEKT/EKT/log"
+	"github.com/EducationEKT/EKT/param"
 )
 
 type DelegateNode struct {
@@ -22,12 +23,12 @@ type DelegateNode struct {
 	client     ektclient.IClient
 }
 
-func NewDelegateNode() *DelegateNode {
+func NewDelegateNode(chainId int64) *DelegateNode {
 	node := &DelegateNode{
 		db:         db.GetDBInst(),
 		seated:     false,
-		blockchain: blockchain.NewBlockChain(1),
-		client:     ektclient.GetInst(),
+		blockchain: blockchain.NewBlockChain(chainId),
+		client:     ektclient.NewChainClient(chainId, param.ChainDelegates(chainId)),
 	}
 	node.dbft = consensus.NewDbftConsensus(node.blockchain, node.client)
 	return node
@@ -72,7 +73,7 @@ func (delegate DelegateNode) sync() {
 			} else {
 				fail, failTime = true, failTime+1
 				log.Debug("Synchronize block at lastHeight %d failed.", lastHeight+1)
-				time.Sleep(blockchain.BackboneBlockInterval)
+				time.Sleep(delegate.blockchain.ChainParams().Interval())
 			}
 		} else {
 			lastHeight = height
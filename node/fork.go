@@ -8,6 +8,7 @@ import (
 	"github.com/EducationEKT/EKT/ctxlog"
 	"github.com/EducationEKT/EKT/ektclient"
 	"github.com/EducationEKT/EKT/encapdb"
+	"github.com/EducationEKT/EKT/param"
 )
 
 type ForkNode struct {
@@ -16,10 +17,10 @@ type ForkNode struct {
 	client     ektclient.IClient
 }
 
-func NewForkNode() *ForkNode {
+func NewForkNode(chainId int64) *ForkNode {
 	node := &ForkNode{
-		blockchain: blockchain.NewBlockChain(1),
-		client:     ektclient.GetInst(),
+		blockchain: blockchain.NewBlockChain(chainId),
+		client:     ektclient.NewChainClient(chainId, param.ChainDelegates(chainId)),
 	}
 	node.dbft = consensus.NewDbftConsensus(node.blockchain, node.client)
 	return node
@@ -65,10 +66,10 @@ func (node ForkNode) GetHeaderByHeight(chainId, height int64) *blockchain.Header
 func (node ForkNode) loop() {
 	fail, failTime := false, 0
 
-	for height := node.blockchain.GetLastHeight(); height < 784286; {
+	for height := node.blockchain.GetLastHeight(); height < param.ForkHeight(param.FORK_LEGACY_END); {
 		if fail {
 			if failTime >= 3 {
-				time.Sleep(blockchain.BackboneBlockInterval)
+				time.Sleep(node.blockchain.ChainParams().Interval())
 			}
 		}
 
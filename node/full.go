@@ -4,11 +4,11 @@ import (
 	"time"
 
 	"github.com/EducationEKT/EKT/blockchain"
-	"github.com/EducationEKT/EKT/conf"
 	"github.com/EducationEKT/EKT/consensus"
 	"github.com/EducationEKT/EKT/ctxlog"
 	"github.com/EducationEKT/EKT/ektclient"
 	"github.com/EducationEKT/EKT/encapdb"
+	"github.com/EducationEKT/EKT/param"
 )
 
 type FullNode struct {
@@ -17,10 +17,10 @@ type FullNode struct {
 	client     ektclient.IClient
 }
 
-func NewFullMode() *FullNode {
+func NewFullMode(chainId int64) *FullNode {
 	node := &FullNode{
-		blockchain: blockchain.NewBlockChain(1),
-		client:     ektclient.GetInst(),
+		blockchain: blockchain.NewBlockChain(chainId),
+		client:     ektclient.NewChainClient(chainId, param.ChainDelegates(chainId)),
 	}
 	node.dbft = consensus.NewDbftConsensus(node.blockchain, node.client)
 	return node
@@ -29,7 +29,7 @@ func NewFullMode() *FullNode {
 func (node FullNode) StartNode() {
 	accounts := node.client.GetGenesisAccounts()
 	if len(accounts) > 0 {
-		conf.EKTConfig.GenesisBlockAccounts = accounts
+		param.SetGenesisAccounts(node.blockchain.ChainId, accounts)
 	}
 	node.recoverFromDB()
 	go node.loop()
@@ -73,7 +73,7 @@ func (node FullNode) loop() {
 	for height := node.blockchain.GetLastHeight() + 1; ; {
 		if fail {
 			if failTime >= 3 {
-				time.Sleep(blockchain.BackboneBlockInterval)
+				time.Sleep(node.blockchain.ChainParams().Interval())
 			}
 		}
 
@@ -7,6 +7,7 @@ import (
 	"github.com/EducationEKT/EKT/blockchain"
 	"github.com/EducationEKT/EKT/conf"
 	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/crosschain"
 	"github.com/EducationEKT/EKT/crypto"
 	"github.com/EducationEKT/EKT/ctxlog"
 	"github.com/EducationEKT/EKT/param"
@@ -19,27 +20,46 @@ const (
 	Adaptive           = "adaptive"
 )
 
-var fullNode Node
+var nodes = make(map[int64]Node)
 var nodeEnv string
 
+// 为配置中的每条链启动一个节点, 每条链有自己的区块链、共识和委托人
 func Init(env string) {
 	nodeEnv = env
+	chains := make(map[int64]Node)
+	for _, chainId := range param.ChainIds() {
+		chains[chainId] = newNode(env, chainId)
+	}
+	nodes = chains
+	// 配置了外部公链时启动跨链中继, 把主链上的跨链交易提交给目标链
+	if len(conf.EKTConfig.CrossChains) > 0 {
+		go crosschain.NewRelay(chains[param.MainChainId].GetBlockChain()).Start()
+	}
+	for chainId, n := range chains {
+		if chainId != param.MainChainId {
+			go n.StartNode()
+		}
+	}
+	chains[param.MainChainId].StartNode()
+}
+
+func newNode(env string, chainId int64) Node {
 	switch env {
-	case NODE_ENV_FULL_SYNC:
-		fullNode = NewFullMode()
 	case NODE_ENV_DELEGETE:
-		fullNode = NewDelegateNode()
+		return NewDelegateNode(chainId)
 	case FORK_ENV:
-		fullNode = NewForkNode()
+		// 只有主链需要重放旧链
+		if chainId == param.MainChainId {
+			return NewForkNode(chainId)
+		}
 	case Adaptive:
-		env := checkEnv()
-		Init(env)
+		return newNode(checkEnv(chainId), chainId)
 	}
-	fullNode.StartNode()
+	return NewFullMode(chainId)
 }
 
-func checkEnv() string {
-	for _, peer := range param.MainChainDelegateNode {
+func checkEnv(chainId int64) string {
+	for _, peer := range param.ChainDelegates(chainId) {
 		if peer.Equal(conf.EKTConfig.Node) {
 			pub, err := crypto.PubKey(conf.EKTConfig.PrivateKey)
 			if err != nil {
@@ -55,7 +75,16 @@ func checkEnv() string {
 }
 
 func GetMainChain() *blockchain.BlockChain {
-	return fullNode.GetBlockChain()
+	return GetChain(param.MainChainId)
+}
+
+// 当前节点没有运行chainId对应的链时返回nil
+func GetChain(chainId int64) *blockchain.BlockChain {
+	n, exist := nodes[chainId]
+	if !exist {
+		return nil
+	}
+	return n.GetBlockChain()
 }
 
 func SuggestFee() int64 {
@@ -65,16 +94,25 @@ func SuggestFee() int64 {
 /*
 	for delegate node
 */
-func BlockFromPeer(clog *ctxlog.ContextLog, block *blockchain.Block) {
-	fullNode.BlockFromPeer(clog, block)
+func BlockFromPeer(chainId int64, clog *ctxlog.ContextLog, block *blockchain.Block) {
+	if n, exist := nodes[chainId]; exist {
+		n.BlockFromPeer(clog, block)
+	}
 }
 
 func VoteFromPeer(vote blockchain.PeerBlockVote) {
-	fullNode.VoteFromPeer(vote)
+	if n, exist := nodes[vote.Vote.BlockchainId]; exist {
+		n.VoteFromPeer(vote)
+	}
 }
 
 func VoteResultFromPeer(votes blockchain.Votes) {
-	fullNode.VoteResultFromPeer(votes)
+	if len(votes) == 0 {
+		return
+	}
+	if n, exist := nodes[votes[0].Vote.BlockchainId]; exist {
+		n.VoteResultFromPeer(votes)
+	}
 }
 
 /*
@@ -82,9 +120,15 @@ func VoteResultFromPeer(votes blockchain.Votes) {
 */
 
 func GetVoteResults(chainId int64, hash string) blockchain.Votes {
-	return fullNode.GetVoteResults(chainId, hash)
+	if n, exist := nodes[chainId]; exist {
+		return n.GetVoteResults(chainId, hash)
+	}
+	return nil
 }
 
 func GetBlockByHeight(chainId, height int64) *blockchain.Header {
-	return fullNode.GetHeaderByHeight(chainId, height)
+	if n, exist := nodes[chainId]; exist {
+		return n.GetHeaderByHeight(chainId, height)
+	}
+	return nil
 }
@@ -0,0 +1,75 @@
+package param
+
+import (
+	"github.com/EducationEKT/EKT/conf"
+	"github.com/EducationEKT/EKT/core/types"
+)
+
+const MainChainId int64 = 1
+
+// EKT在跨公链协议中的publicChainId, 可以在配置中修改
+const DefaultPublicChainId = "EKT"
+
+// 当前节点运行的所有链, 主链排在第一个
+func ChainIds() []int64 {
+	ids := []int64{MainChainId}
+	if conf.EKTConfig == nil {
+		return ids
+	}
+	for _, chain := range conf.EKTConfig.Chains {
+		if chain.ChainId != MainChainId {
+			ids = append(ids, chain.ChainId)
+		}
+	}
+	return ids
+}
+
+func chainConf(chainId int64) *conf.ChainConf {
+	if conf.EKTConfig == nil {
+		return nil
+	}
+	for i := range conf.EKTConfig.Chains {
+		if conf.EKTConfig.Chains[i].ChainId == chainId {
+			return &conf.EKTConfig.Chains[i]
+		}
+	}
+	return nil
+}
+
+// 链的委托人节点, 主链的委托人由env决定
+func ChainDelegates(chainId int64) types.Peers {
+	if chainId == MainChainId {
+		return MainChainDelegateNode
+	}
+	if chain := chainConf(chainId); chain != nil {
+		return chain.Delegates
+	}
+	return nil
+}
+
+// 链的创世块账户
+func GenesisAccounts(chainId int64) []types.Account {
+	if chainId == MainChainId {
+		return conf.EKTConfig.GenesisBlockAccounts
+	}
+	if chain := chainConf(chainId); chain != nil {
+		return chain.GenesisBlockAccounts
+	}
+	return nil
+}
+
+// 全节点从委托人节点获取到创世块账户之后覆盖本地的配置
+func SetGenesisAccounts(chainId int64, accounts []types.Account) {
+	if chainId == MainChainId {
+		conf.EKTConfig.GenesisBlockAccounts = accounts
+	} else if chain := chainConf(chainId); chain != nil {
+		chain.GenesisBlockAccounts = accounts
+	}
+}
+
+func PublicChainId() string {
+	if conf.EKTConfig == nil || conf.EKTConfig.PublicChainId == "" {
+		return DefaultPublicChainId
+	}
+	return conf.EKTConfig.PublicChainId
+}
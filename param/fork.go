@@ -0,0 +1,140 @@
+package param
+
+import (
+	"math"
+
+	"github.com/EducationEKT/EKT/conf"
+)
+
+// 协议升级的名称, 每个升级在各个网络中有各自的激活高度, 从激活高度开始的区块使用新的规则
+const (
+	FORK_LEGACY_END       = "legacyEnd"       // ForkNode重放旧链的结束高度
+	FORK_MERKLER          = "merkler"         // 区块头包含TxRoot和ReceiptRoot
+	FORK_TX_EXTENSION     = "txExtension"     // 交易的有效期和多签名
+	FORK_BIGINT           = "bigint"          // 余额和金额支持超出int64范围
+	FORK_GOVERN           = "govern"          // 共识参数保存在链上，可以通过治理修改
+	FORK_VM_UTC           = "vmUTC"           // 合约中的Date统一使用UTC时区，不再依赖节点所在的时区
+	FORK_CHAIN_EVENT      = "chainEvent"      // 区块头包含系统事件树ChainEvent
+	FORK_VM_GAS           = "vmGas"           // 合约执行按照操作计量gas, 未使用的部分退还
+	FORK_CONTRACT_STORAGE = "contractStorage" // 合约存储保存在每个合约的MPT中, 根节点计入状态树
+	FORK_CONTRACT_LOG     = "contractLog"     // 合约可以通过AWM.emit在交易回执中记录事件日志
+	FORK_BANCOR           = "bancor"          // 系统合约: EKT和Gas之间的Bancor兑换, 激活高度的区块安装合约
+	FORK_KEY_ROTATION     = "keyRotation"     // 系统合约: 账户公钥轮换
+	FORK_TOKEN            = "token"           // 系统合约: Token发行和管理, 转账的Token需要已经发行
+	FORK_CONVERTER        = "converter"       // 系统合约: 部署多连接器的Bancor兑换合约
+	FORK_CROSSCHAIN       = "crossChain"      // 系统合约: 跨链注册、转出和接收, 以及外部地址的转账
+	FORK_BLOCK_LIMITS     = "blockLimits"     // 校验区块时检查交易数量、区块大小和合约交易数量的上限
+)
+
+// 尚未确定激活高度的升级
+const NotScheduled = math.MaxInt64
+
+type Fork struct {
+	Name   string `json:"name"`
+	Height int64  `json:"height"`
+}
+
+type ForkSchedule []Fork
+
+// 2018年10月31日主网重置之前的旧链高度为784286, 重置之后的区块从一开始就使用merkler版本的区块头
+var MainNetForks = ForkSchedule{
+	{FORK_LEGACY_END, 784286},
+	{FORK_MERKLER, 0},
+	{FORK_TX_EXTENSION, NotScheduled},
+	{FORK_BIGINT, NotScheduled},
+	{FORK_GOVERN, NotScheduled},
+	{FORK_VM_UTC, NotScheduled},
+	{FORK_CHAIN_EVENT, NotScheduled},
+	{FORK_VM_GAS, NotScheduled},
+	{FORK_CONTRACT_STORAGE, NotScheduled},
+	{FORK_CONTRACT_LOG, NotScheduled},
+	{FORK_BANCOR, NotScheduled},
+	{FORK_KEY_ROTATION, NotScheduled},
+	{FORK_TOKEN, NotScheduled},
+	{FORK_CONVERTER, NotScheduled},
+	{FORK_CROSSCHAIN, NotScheduled},
+	{FORK_BLOCK_LIMITS, NotScheduled},
+}
+
+var TestNetForks = ForkSchedule{
+	{FORK_LEGACY_END, 0},
+	{FORK_MERKLER, 0},
+	{FORK_TX_EXTENSION, 0},
+	{FORK_BIGINT, 0},
+	{FORK_GOVERN, 0},
+	{FORK_VM_UTC, 0},
+	{FORK_CHAIN_EVENT, 0},
+	{FORK_VM_GAS, 0},
+	{FORK_CONTRACT_STORAGE, 0},
+	{FORK_CONTRACT_LOG, 0},
+	{FORK_BANCOR, 0},
+	{FORK_KEY_ROTATION, 0},
+	{FORK_TOKEN, 0},
+	{FORK_CONVERTER, 0},
+	{FORK_CROSSCHAIN, 0},
+	{FORK_BLOCK_LIMITS, 0},
+}
+
+var LocalNetForks = ForkSchedule{
+	{FORK_LEGACY_END, 0},
+	{FORK_MERKLER, 0},
+	{FORK_TX_EXTENSION, 0},
+	{FORK_BIGINT, 0},
+	{FORK_GOVERN, 0},
+	{FORK_VM_UTC, 0},
+	{FORK_CHAIN_EVENT, 0},
+	{FORK_VM_GAS, 0},
+	{FORK_CONTRACT_STORAGE, 0},
+	{FORK_CONTRACT_LOG, 0},
+	{FORK_BANCOR, 0},
+	{FORK_KEY_ROTATION, 0},
+	{FORK_TOKEN, 0},
+	{FORK_CONVERTER, 0},
+	{FORK_CROSSCHAIN, 0},
+	{FORK_BLOCK_LIMITS, 0},
+}
+
+var forkMapping = make(map[string]ForkSchedule)
+
+// 当前网络的升级计划, 没有初始化时使用LocalNet的计划
+var Forks = LocalNetForks
+
+func InitForks() {
+	forkMapping["mainnet"] = MainNetForks
+	forkMapping["testnet"] = TestNetForks
+	forkMapping["localnet"] = LocalNetForks
+	if schedule, exist := forkMapping[conf.EKTConfig.Env]; exist {
+		Forks = schedule
+	}
+}
+
+func (schedule ForkSchedule) Height(fork string) int64 {
+	for _, f := range schedule {
+		if f.Name == fork {
+			return f.Height
+		}
+	}
+	return NotScheduled
+}
+
+// 升级fork在区块高度height是否已经激活
+func (schedule ForkSchedule) IsActive(fork string, height int64) bool {
+	return height >= schedule.Height(fork)
+}
+
+func ForkHeight(fork string) int64 {
+	return Forks.Height(fork)
+}
+
+func IsActive(fork string, height int64) bool {
+	return Forks.IsActive(fork, height)
+}
+
+// 升级是否在高度为height的区块激活, 激活高度为0的升级在创世块之后的第一个区块激活
+func ActivatesAt(fork string, height int64) bool {
+	activation := ForkHeight(fork)
+	if activation < 1 {
+		activation = 1
+	}
+	return height == activation
+}
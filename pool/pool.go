@@ -43,6 +43,17 @@ func (pool *TxPool) Pop(size int) []*userevent.Transaction {
 	return txs
 }
 
+// 将打包时取出但没有放入区块的交易放回到队列的头部, 已经从交易池删除的交易不再放回
+func (pool *TxPool) Restore(txs ...*userevent.Transaction) {
+	restored := make([]*userevent.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if pool.All.Get(tx.TransactionId()) != nil {
+			restored = append(restored, tx)
+		}
+	}
+	pool.List.Restore(restored...)
+}
+
 func (pool *TxPool) Notify(txs []userevent.Transaction) {
 	for _, tx := range txs {
 		pool.All.Delete(tx.TransactionId())
@@ -51,6 +62,30 @@ func (pool *TxPool) Notify(txs []userevent.Transaction) {
 	}
 }
 
+// 将交易从交易池中彻底删除, 同一个用户nonce更大的交易无法再执行, 一起删除
+func (pool *TxPool) Drop(tx *userevent.Transaction) {
+	pool.All.Delete(tx.TransactionId())
+	pool.List.Notify(*tx)
+	for _, later := range pool.UsersTxs.Drop(*tx) {
+		pool.All.Delete(later.TransactionId())
+		pool.List.Notify(*later)
+	}
+}
+
+// 删除在指定区块高度和时间戳上已经过期的交易
+func (pool *TxPool) DropExpired(height, timestamp int64) {
+	expired := make([]*userevent.Transaction, 0)
+	pool.All.Range(func(hash string, tx *userevent.Transaction) bool {
+		if tx.Expired(height, timestamp) {
+			expired = append(expired, tx)
+		}
+		return true
+	})
+	for _, tx := range expired {
+		pool.Drop(tx)
+	}
+}
+
 func (pool *TxPool) GetUserTxs(address string) *UserTxs {
 	pool.UsersTxs.locker.RLock()
 	result := pool.UsersTxs.M[address]
@@ -136,6 +136,29 @@ func (sorted *UserTxs) Remove(tx userevent.Transaction) {
 	sorted.Nonces.Delete(tx.Nonce)
 }
 
+// 删除一个未被打包的交易，之后可以使用相同的nonce重新发送交易
+// 该用户nonce更大的交易在被删除的交易之后已经无法执行, 一并删除并返回
+func (sorted *UserTxs) Drop(tx userevent.Transaction) []*userevent.Transaction {
+	dropped := make([]*userevent.Transaction, 0)
+	newNonces := NewNonceList()
+	for _, nonce := range *sorted.Nonces {
+		if nonce < tx.Nonce {
+			newNonces.Insert(nonce)
+			continue
+		}
+		if nonce > tx.Nonce && sorted.Txs[nonce] != nil {
+			dropped = append(dropped, sorted.Txs[nonce])
+		}
+		delete(sorted.Txs, nonce)
+	}
+	sorted.Nonces = newNonces
+	if sorted.Nonce >= tx.Nonce {
+		sorted.Nonce = tx.Nonce - 1
+	}
+	sorted.updateIndex()
+	return dropped
+}
+
 type UsersTxs struct {
 	M      map[string]*UserTxs `json:"m"`
 	locker sync.RWMutex
@@ -189,3 +212,13 @@ func (m *UsersTxs) Notify(tx userevent.Transaction) ([]*userevent.Transaction, b
 	}
 	return nil, false
 }
+
+func (m *UsersTxs) Drop(tx userevent.Transaction) []*userevent.Transaction {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	userTxs := m.M[hex.EncodeToString(tx.From)]
+	if userTxs != nil {
+		return userTxs.Drop(tx)
+	}
+	return nil
+}
@@ -38,3 +38,15 @@ func TestSortedSave(t *testing.T) {
 	userTx.Save(transactionSeven)
 	fmt.Println("Seven save index", userTx.Index)
 }
+
+func TestSortedDrop(t *testing.T) {
+	from, _ := hex.DecodeString("56b92dfdbfbd7d32ea5deb6ca05ea8d695ed727c9d9a7536e345646608e339dc")
+	userTx := NewUserTxs(1)
+	for nonce := int64(2); nonce <= 5; nonce++ {
+		userTx.Save(userevent.NewTransaction(from, from, time.Now().Unix(), 50, 50, nonce, "test", " "))
+	}
+	dropped := userTx.Drop(*userTx.Txs[3])
+	if len(dropped) != 2 || userTx.Nonce != 2 || len(*userTx.Nonces) != 1 || userTx.Txs[4] != nil || userTx.Txs[5] != nil {
+		t.Fatal("later nonces of the dropped transaction should be dropped too")
+	}
+}
@@ -32,6 +32,19 @@ func (list *TxTimedList) Put(txs ...*userevent.Transaction) {
 	list.locker.Unlock()
 }
 
+func (list *TxTimedList) Restore(txs ...*userevent.Transaction) {
+	list.locker.Lock()
+	defer list.locker.Unlock()
+	restored := make([]*userevent.Transaction, 0, len(txs)+len(list.List))
+	for _, tx := range txs {
+		if !list.M[tx.TransactionId()] {
+			list.M[tx.TransactionId()] = true
+			restored = append(restored, tx)
+		}
+	}
+	list.List = append(restored, list.List...)
+}
+
 func (list *TxTimedList) Pop(size int) []*userevent.Transaction {
 	list.locker.Lock()
 	defer list.locker.Unlock()
@@ -56,6 +69,7 @@ func (list *TxTimedList) Notify(tx userevent.Transaction) {
 	list.locker.Lock()
 	defer list.locker.Unlock()
 	if list.M[tx.TransactionId()] {
+		delete(list.M, tx.TransactionId())
 		for i, _tx := range list.List {
 			if bytes.Equal(tx.TxId(), _tx.TxId()) {
 				list.List = append(list.List[:i], list.List[i+1:]...)
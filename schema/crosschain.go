@@ -0,0 +1,7 @@
+package schema
+
+import "fmt"
+
+func CrossChainRelayHeightKey(chainId int64) []byte {
+	return []byte(fmt.Sprintf("CrossChainRelayHeight_%d", chainId))
+}
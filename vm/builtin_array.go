@@ -39,6 +39,7 @@ func builtinArray_toLocaleString(call FunctionCall) Value {
 	separator := ","
 	thisObject := call.thisObject()
 	length := int64(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(length)
 	if length == 0 {
 		return toValue_string("")
 	}
@@ -57,6 +58,7 @@ func builtinArray_toLocaleString(call FunctionCall) Value {
 			stringValue = toLocaleString.call(call.runtime, toValue_object(object)).string()
 		}
 		stringList = append(stringList, stringValue)
+		call.runtime.useStringGas(len(stringValue) + len(separator))
 	}
 	return toValue_string(strings.Join(stringList, separator))
 }
@@ -71,6 +73,7 @@ func builtinArray_concat(call FunctionCall) Value {
 			object := item._object()
 			if isArray(object) {
 				length := object.get("length").number().int64
+				call.runtime.useElementGas(length)
 				for index := int64(0); index < length; index += 1 {
 					name := strconv.FormatInt(index, 10)
 					if object.hasProperty(name) {
@@ -92,6 +95,7 @@ func builtinArray_concat(call FunctionCall) Value {
 func builtinArray_shift(call FunctionCall) Value {
 	thisObject := call.thisObject()
 	length := int64(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(length)
 	if 0 == length {
 		thisObject.put("length", toValue_int64(0), true)
 		return Value{}
@@ -128,6 +132,7 @@ func builtinArray_push(call FunctionCall) Value {
 func builtinArray_pop(call FunctionCall) Value {
 	thisObject := call.thisObject()
 	length := int64(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(length)
 	if 0 == length {
 		thisObject.put("length", toValue_uint32(0), true)
 		return Value{}
@@ -148,6 +153,7 @@ func builtinArray_join(call FunctionCall) Value {
 	}
 	thisObject := call.thisObject()
 	length := int64(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(length)
 	if length == 0 {
 		return toValue_string("")
 	}
@@ -161,6 +167,7 @@ func builtinArray_join(call FunctionCall) Value {
 			stringValue = value.string()
 		}
 		stringList = append(stringList, stringValue)
+		call.runtime.useStringGas(len(stringValue) + len(separator))
 	}
 	return toValue_string(strings.Join(stringList, separator))
 }
@@ -168,6 +175,7 @@ func builtinArray_join(call FunctionCall) Value {
 func builtinArray_splice(call FunctionCall) Value {
 	thisObject := call.thisObject()
 	length := int64(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(length)
 
 	start := valueToRangeIndex(call.Argument(0), length, false)
 	deleteCount := length - start
@@ -249,6 +257,7 @@ func builtinArray_slice(call FunctionCall) Value {
 	thisObject := call.thisObject()
 
 	length := int64(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(length)
 	start, end := rangeStartEnd(call.ArgumentList, length, false)
 
 	if start >= end {
@@ -271,6 +280,7 @@ func builtinArray_slice(call FunctionCall) Value {
 func builtinArray_unshift(call FunctionCall) Value {
 	thisObject := call.thisObject()
 	length := int64(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(length)
 	itemList := call.ArgumentList
 	itemCount := int64(len(itemList))
 
@@ -296,6 +306,7 @@ func builtinArray_unshift(call FunctionCall) Value {
 func builtinArray_reverse(call FunctionCall) Value {
 	thisObject := call.thisObject()
 	length := int64(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(length)
 
 	lower := struct {
 		name   string
@@ -339,6 +350,8 @@ func builtinArray_reverse(call FunctionCall) Value {
 }
 
 func sortCompare(thisObject *_object, index0, index1 uint, compare *_object) int {
+	// 排序按照比较的次数计量, 避免最坏情况下的比较次数不受限制
+	thisObject.runtime.useGas(GAS_COPY)
 	j := struct {
 		name    string
 		exists  bool
@@ -455,6 +468,7 @@ func arraySortQuickSort(thisObject *_object, left, right uint, compare *_object)
 func builtinArray_sort(call FunctionCall) Value {
 	thisObject := call.thisObject()
 	length := uint(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(int64(length))
 	compareValue := call.Argument(0)
 	compare := compareValue._object()
 	if compareValue.IsUndefined() {
@@ -474,6 +488,7 @@ func builtinArray_isArray(call FunctionCall) Value {
 func builtinArray_indexOf(call FunctionCall) Value {
 	thisObject, matchValue := call.thisObject(), call.Argument(0)
 	if length := int64(toUint32(thisObject.get("length"))); length > 0 {
+		call.runtime.useElementGas(length)
 		index := int64(0)
 		if len(call.ArgumentList) > 1 {
 			index = call.Argument(1).number().int64
@@ -502,6 +517,7 @@ func builtinArray_indexOf(call FunctionCall) Value {
 func builtinArray_lastIndexOf(call FunctionCall) Value {
 	thisObject, matchValue := call.thisObject(), call.Argument(0)
 	length := int64(toUint32(thisObject.get("length")))
+	call.runtime.useElementGas(length)
 	index := length - 1
 	if len(call.ArgumentList) > 1 {
 		index = call.Argument(1).number().int64
@@ -532,6 +548,7 @@ func builtinArray_every(call FunctionCall) Value {
 	this := toValue_object(thisObject)
 	if iterator := call.Argument(0); iterator.isCallable() {
 		length := int64(toUint32(thisObject.get("length")))
+		call.runtime.useElementGas(length)
 		callThis := call.Argument(1)
 		for index := int64(0); index < length; index++ {
 			if key := arrayIndexToString(index); thisObject.hasProperty(key) {
@@ -551,6 +568,7 @@ func builtinArray_some(call FunctionCall) Value {
 	this := toValue_object(thisObject)
 	if iterator := call.Argument(0); iterator.isCallable() {
 		length := int64(toUint32(thisObject.get("length")))
+		call.runtime.useElementGas(length)
 		callThis := call.Argument(1)
 		for index := int64(0); index < length; index++ {
 			if key := arrayIndexToString(index); thisObject.hasProperty(key) {
@@ -569,6 +587,7 @@ func builtinArray_forEach(call FunctionCall) Value {
 	this := toValue_object(thisObject)
 	if iterator := call.Argument(0); iterator.isCallable() {
 		length := int64(toUint32(thisObject.get("length")))
+		call.runtime.useElementGas(length)
 		callThis := call.Argument(1)
 		for index := int64(0); index < length; index++ {
 			if key := arrayIndexToString(index); thisObject.hasProperty(key) {
@@ -585,6 +604,7 @@ func builtinArray_map(call FunctionCall) Value {
 	this := toValue_object(thisObject)
 	if iterator := call.Argument(0); iterator.isCallable() {
 		length := int64(toUint32(thisObject.get("length")))
+		call.runtime.useElementGas(length)
 		callThis := call.Argument(1)
 		values := make([]Value, length)
 		for index := int64(0); index < length; index++ {
@@ -604,6 +624,7 @@ func builtinArray_filter(call FunctionCall) Value {
 	this := toValue_object(thisObject)
 	if iterator := call.Argument(0); iterator.isCallable() {
 		length := int64(toUint32(thisObject.get("length")))
+		call.runtime.useElementGas(length)
 		callThis := call.Argument(1)
 		values := make([]Value, 0)
 		for index := int64(0); index < length; index++ {
@@ -626,6 +647,7 @@ func builtinArray_reduce(call FunctionCall) Value {
 		initial := len(call.ArgumentList) > 1
 		start := call.Argument(1)
 		length := int64(toUint32(thisObject.get("length")))
+		call.runtime.useElementGas(length)
 		index := int64(0)
 		if length > 0 || initial {
 			var accumulator Value
@@ -658,6 +680,7 @@ func builtinArray_reduceRight(call FunctionCall) Value {
 		initial := len(call.ArgumentList) > 1
 		start := call.Argument(1)
 		length := int64(toUint32(thisObject.get("length")))
+		call.runtime.useElementGas(length)
 		if length > 0 || initial {
 			index := length - 1
 			var accumulator Value
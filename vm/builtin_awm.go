@@ -12,11 +12,13 @@ import (
 )
 
 func builtinAWM_Sha3_256(call FunctionCall) Value {
+	call.Otto.runtime.useGas(GAS_HASH)
 	param := call.Argument(0).string()
 	return toValue_string(hex.EncodeToString(crypto.Sha3_256([]byte(param))))
 }
 
 func builtinAWM_secp256k1_ecrecover(call FunctionCall) Value {
+	call.Otto.runtime.useGas(GAS_HASH)
 	msg := call.Argument(0).string()
 	sign := call.Argument(1).string()
 	msg_b, err := hex.DecodeString(msg)
@@ -35,6 +37,7 @@ func builtinAWM_secp256k1_ecrecover(call FunctionCall) Value {
 }
 
 func builtinAWM_secp256k1_verify(call FunctionCall) Value {
+	call.Otto.runtime.useGas(GAS_HASH)
 	msg := call.Argument(0).string()
 	sign := call.Argument(1).string()
 	address := call.Argument(2).string()
@@ -59,7 +62,13 @@ func builtinAWM_contract_call(call FunctionCall) Value {
 		return falseValue
 	}
 
+	call.Otto.runtime.useGas(GAS_CONTRACT)
 	vm := call.Otto.clone()
+	// 被调用的合约使用调用方剩余的gas, 执行结束之后计入调用方的消耗
+	if call.Otto.runtime.metered {
+		vm.SetGasLimit(call.Otto.runtime.gasRemaining())
+		defer call.Otto.runtime.useChildGas(vm)
+	}
 	contractAddr := call.ArgumentList[0].String()
 	if strings.HasPrefix(contractAddr, "0x") {
 		contractAddr = contractAddr[2:]
@@ -82,6 +91,10 @@ func builtinAWM_contract_call(call FunctionCall) Value {
 	log.LogErr(vm.Set("sender", hex.EncodeToString(call.Otto.tx.To)))
 
 	_, err = vm.Run(fmt.Sprintf("var result = %s.apply(null, args);", method.String()))
+	if err == TIMEOUT_ERROR {
+		// 被调用的合约收到了中断, 调用方同样需要退出
+		panic(TIMEOUT_ERROR)
+	}
 	if err != nil {
 		return falseValue
 	}
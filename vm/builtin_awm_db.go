@@ -5,9 +5,13 @@ import "encoding/hex"
 const AWM_DB_PREFIX = "_AWM_CONTRACT_DB_"
 
 func builtin_awm_db_get(call FunctionCall) Value {
+	call.Otto.runtime.useGas(GAS_DB_READ)
 	if call.Otto.tx == nil {
 		return falseValue
 	}
+	if call.Otto.useStorageTrie() {
+		return toValue_string(string(call.Otto.storage.get(call.Otto.tx.To, call.Argument(0).string())))
+	}
 	contractAddr := hex.EncodeToString(call.Otto.tx.To)
 	key := AWM_DB_PREFIX + contractAddr + call.Argument(0).string()
 	value, err := call.Otto.db.Get([]byte(key))
@@ -18,9 +22,14 @@ func builtin_awm_db_get(call FunctionCall) Value {
 }
 
 func builtin_awm_db_set(call FunctionCall) Value {
+	call.Otto.runtime.useGas(GAS_DB_WRITE)
 	if call.Otto.tx == nil {
 		return falseValue
 	}
+	if call.Otto.useStorageTrie() {
+		call.Otto.storage.set(call.Otto.tx.To, call.Argument(0).string(), []byte(call.Argument(1).string()))
+		return toValue_bool(true)
+	}
 	contractAddr := hex.EncodeToString(call.Otto.tx.To)
 	key := AWM_DB_PREFIX + contractAddr + call.Argument(0).string()
 	value := call.Argument(1).string()
@@ -32,9 +41,14 @@ func builtin_awm_db_set(call FunctionCall) Value {
 }
 
 func builtin_awm_db_delete(call FunctionCall) Value {
+	call.Otto.runtime.useGas(GAS_DB_WRITE)
 	if call.Otto.tx == nil {
 		return falseValue
 	}
+	if call.Otto.useStorageTrie() {
+		call.Otto.storage.delete(call.Otto.tx.To, call.Argument(0).string())
+		return toValue_bool(true)
+	}
 	contractAddr := hex.EncodeToString(call.Otto.tx.To)
 	key := AWM_DB_PREFIX + contractAddr + call.Argument(0).string()
 	err := call.Otto.db.Delete([]byte(key))
@@ -0,0 +1,39 @@
+package vm
+
+import (
+	"encoding/json"
+
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/param"
+)
+
+// AWM.emit(name, data) 记录合约事件, data序列化为JSON, 合约执行成功之后写入交易回执
+func builtinAWM_emit(call FunctionCall) Value {
+	if call.Otto.chain == nil || !param.IsActive(param.FORK_CONTRACT_LOG, call.Otto.chain.GetHeight()) {
+		panic(call.runtime.panicTypeError("AWM.emit is not a function"))
+	}
+	call.Otto.runtime.useGas(GAS_LOG)
+	if call.Otto.tx == nil || len(call.ArgumentList) == 0 {
+		return falseValue
+	}
+	value, err := call.Argument(1).Export()
+	if err != nil {
+		return falseValue
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return falseValue
+	}
+	call.Otto.logs = append(call.Otto.logs, userevent.ContractLog{
+		Address: append([]byte{}, call.Otto.tx.To...),
+		Name:    call.Argument(0).string(),
+		Index:   len(call.Otto.logs),
+		Data:    data,
+	})
+	return toValue_bool(true)
+}
+
+// 本次执行记录的事件日志
+func (otto *Otto) Logs() []userevent.ContractLog {
+	return otto.logs
+}
@@ -0,0 +1,30 @@
+package vm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/db"
+)
+
+func TestEmit(t *testing.T) {
+	vm := NewVM(testChain{}, db.NewMemKVDatabase())
+	if value, _ := vm.Run(`AWM.emit("transfer", {"to": "abc", "amount": 10})`); value.String() != "false" {
+		t.Fatal("emit without transaction should be ignored")
+	}
+
+	tx := userevent.Transaction{To: crypto.Sha3_256([]byte("contract"))}
+	vm.tx = &tx
+	if _, err := vm.Run(`AWM.emit("transfer", {"to": "abc", "amount": 10}); AWM.emit("approve");`); err != nil {
+		t.Fatal(err)
+	}
+	logs := vm.Logs()
+	if len(logs) != 2 || logs[0].Name != "transfer" || logs[1].Index != 1 || !bytes.Equal(logs[0].Address, tx.To) {
+		t.Fatal("logs mismatch", logs)
+	}
+	if string(logs[0].Data) != `{"amount":10,"to":"abc"}` || string(logs[1].Data) != "null" {
+		t.Fatal("log data mismatch", string(logs[0].Data), string(logs[1].Data))
+	}
+}
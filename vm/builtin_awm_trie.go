@@ -9,11 +9,13 @@ import (
 )
 
 func builtin_awm_mpt_init(call FunctionCall) Value {
+	call.Otto.runtime.useGas(GAS_DB_WRITE)
 	mpt := MPTPlus.NewMTP(db.GetDBInst())
 	return toValue_string(hex.EncodeToString(mpt.Root))
 }
 
 func builtin_awm_mpt_insert(call FunctionCall) Value {
+	call.Otto.runtime.useGas(GAS_DB_WRITE)
 	root, err := hex.DecodeString(call.Argument(0).string())
 	key := call.Argument(1).string()
 	value := call.Argument(2).string()
@@ -28,6 +30,7 @@ func builtin_awm_mpt_insert(call FunctionCall) Value {
 }
 
 func builtin_awm_mpt_get(call FunctionCall) Value {
+	call.Otto.runtime.useGas(GAS_DB_READ)
 	root, err := hex.DecodeString(call.Argument(0).string())
 	key := crypto.Sha3_256([]byte(call.Argument(1).string()))
 	if err != nil {
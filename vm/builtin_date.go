@@ -11,6 +11,14 @@ const (
 	builtinDate_goTimeLayout     = "15:04:05 MST"
 )
 
+// 合约中的本地时区, FORK_VM_UTC激活之后为UTC, 保证所有节点的执行结果一致
+func (self *_runtime) timeLocation() *Time.Location {
+	if self.location == nil {
+		return Time.Local
+	}
+	return self.location
+}
+
 func builtinDate(call FunctionCall) Value {
 	date := &_dateObject{}
 	date.SetTime(Time.Unix(call.runtime.timestamp/1000, (call.runtime.timestamp%1000)*1e6))
@@ -21,7 +29,7 @@ func builtinNewDate(self *_object, argumentList []Value) Value {
 	if len(argumentList) == 0 {
 		return toValue_object(self.runtime.newDate(float64(self.runtime.timestamp)))
 	}
-	return toValue_object(self.runtime.newDate(newDateTime(argumentList, Time.Local)))
+	return toValue_object(self.runtime.newDate(newDateTime(argumentList, self.runtime.timeLocation())))
 }
 
 func builtinDate_toString(call FunctionCall) Value {
@@ -29,7 +37,7 @@ func builtinDate_toString(call FunctionCall) Value {
 	if date.isNaN {
 		return toValue_string("Invalid Date")
 	}
-	return toValue_string(date.Time().Local().Format(builtinDate_goDateTimeLayout))
+	return toValue_string(date.Time().In(call.runtime.timeLocation()).Format(builtinDate_goDateTimeLayout))
 }
 
 func builtinDate_toDateString(call FunctionCall) Value {
@@ -37,7 +45,7 @@ func builtinDate_toDateString(call FunctionCall) Value {
 	if date.isNaN {
 		return toValue_string("Invalid Date")
 	}
-	return toValue_string(date.Time().Local().Format(builtinDate_goDateLayout))
+	return toValue_string(date.Time().In(call.runtime.timeLocation()).Format(builtinDate_goDateLayout))
 }
 
 func builtinDate_toTimeString(call FunctionCall) Value {
@@ -45,7 +53,7 @@ func builtinDate_toTimeString(call FunctionCall) Value {
 	if date.isNaN {
 		return toValue_string("Invalid Date")
 	}
-	return toValue_string(date.Time().Local().Format(builtinDate_goTimeLayout))
+	return toValue_string(date.Time().In(call.runtime.timeLocation()).Format(builtinDate_goTimeLayout))
 }
 
 func builtinDate_toUTCString(call FunctionCall) Value {
@@ -137,7 +145,7 @@ func _builtinDate_beforeSet(call FunctionCall, argumentLimit int, timeLocal bool
 	}
 	baseTime := date.Time()
 	if timeLocal {
-		baseTime = baseTime.Local()
+		baseTime = baseTime.In(call.runtime.timeLocation())
 	}
 	ecmaTime := ecmaTime(baseTime)
 	return object, &date, &ecmaTime, valueList
@@ -163,7 +171,7 @@ func builtinDate_toLocaleString(call FunctionCall) Value {
 	if date.isNaN {
 		return toValue_string("Invalid Date")
 	}
-	return toValue_string(date.Time().Local().Format("2006-01-02 15:04:05"))
+	return toValue_string(date.Time().In(call.runtime.timeLocation()).Format("2006-01-02 15:04:05"))
 }
 
 // This is a placeholder
@@ -172,7 +180,7 @@ func builtinDate_toLocaleDateString(call FunctionCall) Value {
 	if date.isNaN {
 		return toValue_string("Invalid Date")
 	}
-	return toValue_string(date.Time().Local().Format("2006-01-02"))
+	return toValue_string(date.Time().In(call.runtime.timeLocation()).Format("2006-01-02"))
 }
 
 // This is a placeholder
@@ -181,7 +189,7 @@ func builtinDate_toLocaleTimeString(call FunctionCall) Value {
 	if date.isNaN {
 		return toValue_string("Invalid Date")
 	}
-	return toValue_string(date.Time().Local().Format("15:04:05"))
+	return toValue_string(date.Time().In(call.runtime.timeLocation()).Format("15:04:05"))
 }
 
 func builtinDate_valueOf(call FunctionCall) Value {
@@ -199,7 +207,7 @@ func builtinDate_getYear(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	return toValue_int(date.Time().Local().Year() - 1900)
+	return toValue_int(date.Time().In(call.runtime.timeLocation()).Year() - 1900)
 }
 
 func builtinDate_getFullYear(call FunctionCall) Value {
@@ -209,7 +217,7 @@ func builtinDate_getFullYear(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	return toValue_int(date.Time().Local().Year())
+	return toValue_int(date.Time().In(call.runtime.timeLocation()).Year())
 }
 
 func builtinDate_getUTCFullYear(call FunctionCall) Value {
@@ -225,7 +233,7 @@ func builtinDate_getMonth(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	return toValue_int(dateFromGoMonth(date.Time().Local().Month()))
+	return toValue_int(dateFromGoMonth(date.Time().In(call.runtime.timeLocation()).Month()))
 }
 
 func builtinDate_getUTCMonth(call FunctionCall) Value {
@@ -241,7 +249,7 @@ func builtinDate_getDate(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	return toValue_int(date.Time().Local().Day())
+	return toValue_int(date.Time().In(call.runtime.timeLocation()).Day())
 }
 
 func builtinDate_getUTCDate(call FunctionCall) Value {
@@ -258,7 +266,7 @@ func builtinDate_getDay(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	return toValue_int(dateFromGoDay(date.Time().Local().Weekday()))
+	return toValue_int(dateFromGoDay(date.Time().In(call.runtime.timeLocation()).Weekday()))
 }
 
 func builtinDate_getUTCDay(call FunctionCall) Value {
@@ -274,7 +282,7 @@ func builtinDate_getHours(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	return toValue_int(date.Time().Local().Hour())
+	return toValue_int(date.Time().In(call.runtime.timeLocation()).Hour())
 }
 
 func builtinDate_getUTCHours(call FunctionCall) Value {
@@ -290,7 +298,7 @@ func builtinDate_getMinutes(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	return toValue_int(date.Time().Local().Minute())
+	return toValue_int(date.Time().In(call.runtime.timeLocation()).Minute())
 }
 
 func builtinDate_getUTCMinutes(call FunctionCall) Value {
@@ -306,7 +314,7 @@ func builtinDate_getSeconds(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	return toValue_int(date.Time().Local().Second())
+	return toValue_int(date.Time().In(call.runtime.timeLocation()).Second())
 }
 
 func builtinDate_getUTCSeconds(call FunctionCall) Value {
@@ -322,7 +330,7 @@ func builtinDate_getMilliseconds(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	return toValue_int(date.Time().Local().Nanosecond() / (100 * 100 * 100))
+	return toValue_int(date.Time().In(call.runtime.timeLocation()).Nanosecond() / (100 * 100 * 100))
 }
 
 func builtinDate_getUTCMilliseconds(call FunctionCall) Value {
@@ -338,7 +346,7 @@ func builtinDate_getTimezoneOffset(call FunctionCall) Value {
 	if date.isNaN {
 		return NaNValue()
 	}
-	timeLocal := date.Time().Local()
+	timeLocal := date.Time().In(call.runtime.timeLocation())
 	// Is this kosher?
 	timeLocalAsUTC := Time.Date(
 		timeLocal.Year(),
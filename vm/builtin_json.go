@@ -23,7 +23,9 @@ func builtinJSON_parse(call FunctionCall) Value {
 	}
 
 	var root interface{}
-	err := json.Unmarshal([]byte(call.Argument(0).string()), &root)
+	input := call.Argument(0).string()
+	call.runtime.useStringGas(len(input))
+	err := json.Unmarshal([]byte(input), &root)
 	if err != nil {
 		panic(call.runtime.panicSyntaxError(err.Error()))
 	}
@@ -115,6 +117,7 @@ func builtinJSON_stringify(call FunctionCall) Value {
 	if replacer != nil {
 		if isArray(replacer) {
 			length := objectLength(replacer)
+			call.runtime.useElementGas(int64(length))
 			seen := map[string]bool{}
 			propertyList := make([]string, length)
 			length = 0
@@ -189,6 +192,7 @@ func builtinJSON_stringify(call FunctionCall) Value {
 		json.Indent(&valueJSON1, valueJSON, "", ctx.gap)
 		valueJSON = valueJSON1.Bytes()
 	}
+	call.runtime.useStringGas(len(valueJSON))
 	return toValue_string(string(valueJSON))
 }
 
@@ -265,6 +269,7 @@ func builtinJSON_stringifyWalk(ctx _builtinJSON_stringifyContext, key string, ho
 			default:
 				panic(ctx.call.runtime.panicTypeError(fmt.Sprintf("JSON.stringify: invalid length: %v (%[1]T)", value)))
 			}
+			ctx.call.runtime.useElementGas(int64(length))
 			array := make([]interface{}, length)
 			for index, _ := range array {
 				name := arrayIndexToString(int64(index))
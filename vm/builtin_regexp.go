@@ -43,6 +43,7 @@ func builtinRegExp_toString(call FunctionCall) Value {
 func builtinRegExp_exec(call FunctionCall) Value {
 	thisObject := call.thisObject()
 	target := call.Argument(0).string()
+	call.runtime.useStringGas(len(target))
 	match, result := execRegExp(thisObject, target)
 	if !match {
 		return nullValue
@@ -53,6 +54,7 @@ func builtinRegExp_exec(call FunctionCall) Value {
 func builtinRegExp_test(call FunctionCall) Value {
 	thisObject := call.thisObject()
 	target := call.Argument(0).string()
+	call.runtime.useStringGas(len(target))
 	match, _ := execRegExp(thisObject, target)
 	return toValue_bool(match)
 }
@@ -64,8 +64,11 @@ func builtinString_concat(call FunctionCall) Value {
 	checkObjectCoercible(call.runtime, call.This)
 	var value bytes.Buffer
 	value.WriteString(call.This.string())
+	call.runtime.useStringGas(value.Len())
 	for _, item := range call.ArgumentList {
-		value.WriteString(item.string())
+		str := item.string()
+		call.runtime.useStringGas(len(str))
+		value.WriteString(str)
 	}
 	return toValue_string(value.String())
 }
@@ -74,6 +77,7 @@ func builtinString_indexOf(call FunctionCall) Value {
 	checkObjectCoercible(call.runtime, call.This)
 	value := call.This.string()
 	target := call.Argument(0).string()
+	call.runtime.useStringGas(len(value))
 	if 2 > len(call.ArgumentList) {
 		return toValue_int(strings.Index(value, target))
 	}
@@ -97,6 +101,7 @@ func builtinString_lastIndexOf(call FunctionCall) Value {
 	checkObjectCoercible(call.runtime, call.This)
 	value := call.This.string()
 	target := call.Argument(0).string()
+	call.runtime.useStringGas(len(value))
 	if 2 > len(call.ArgumentList) || call.ArgumentList[1].IsUndefined() {
 		return toValue_int(strings.LastIndex(value, target))
 	}
@@ -122,6 +127,7 @@ func builtinString_lastIndexOf(call FunctionCall) Value {
 func builtinString_match(call FunctionCall) Value {
 	checkObjectCoercible(call.runtime, call.This)
 	target := call.This.string()
+	call.runtime.useStringGas(len(target))
 	matcherValue := call.Argument(0)
 	matcher := matcherValue._object()
 	if !matcherValue.IsObject() || matcher.class != "RegExp" {
@@ -144,6 +150,7 @@ func builtinString_match(call FunctionCall) Value {
 			return Value{} // !match
 		}
 		matchCount = len(result)
+		call.runtime.useElementGas(int64(matchCount))
 		valueArray := make([]Value, matchCount)
 		for index := 0; index < matchCount; index++ {
 			valueArray[index] = toValue_string(target[result[index][0]:result[index][1]])
@@ -191,6 +198,7 @@ func builtinString_findAndReplaceString(input []byte, lastIndex int, match []int
 func builtinString_replace(call FunctionCall) Value {
 	checkObjectCoercible(call.runtime, call.This)
 	target := []byte(call.This.string())
+	call.runtime.useStringGas(len(target))
 	searchValue := call.Argument(0)
 	searchObject := searchValue._object()
 
@@ -238,6 +246,7 @@ func builtinString_replace(call FunctionCall) Value {
 				argumentList[matchCount+0] = toValue_int(match[0])
 				argumentList[matchCount+1] = toValue_string(target)
 				replacement := replace.call(Value{}, argumentList, false, nativeFrame).string()
+				call.runtime.useStringGas(len(replacement))
 				result = append(result, []byte(replacement)...)
 				lastIndex = match[1]
 			}
@@ -245,7 +254,10 @@ func builtinString_replace(call FunctionCall) Value {
 		} else {
 			replace := []byte(replaceValue.string())
 			for _, match := range found {
+				// 按照替换之后增加的长度计量, $`和$'可以让结果的长度远大于原字符串
+				size := len(result)
 				result = builtinString_findAndReplaceString(result, lastIndex, match, target, replace)
+				call.runtime.useStringGas(len(result) - size)
 				lastIndex = match[1]
 			}
 		}
@@ -265,6 +277,7 @@ func builtinString_replace(call FunctionCall) Value {
 func builtinString_search(call FunctionCall) Value {
 	checkObjectCoercible(call.runtime, call.This)
 	target := call.This.string()
+	call.runtime.useStringGas(len(target))
 	searchValue := call.Argument(0)
 	search := searchValue._object()
 	if !searchValue.IsObject() || search.class != "RegExp" {
@@ -291,6 +304,7 @@ func stringSplitMatch(target string, targetLength int64, index uint, search stri
 func builtinString_split(call FunctionCall) Value {
 	checkObjectCoercible(call.runtime, call.This)
 	target := call.This.string()
+	call.runtime.useStringGas(len(target))
 
 	separatorValue := call.Argument(0)
 	limitValue := call.Argument(1)
@@ -312,6 +326,7 @@ func builtinString_split(call FunctionCall) Value {
 		search := separatorValue._object().regExpValue().regularExpression
 		valueArray := []Value{}
 		result := search.FindAllStringSubmatchIndex(target, -1)
+		call.runtime.useElementGas(int64(len(result)))
 		lastIndex := 0
 		found := 0
 
@@ -380,6 +395,7 @@ func builtinString_split(call FunctionCall) Value {
 			split = split[:limit]
 		}
 
+		call.runtime.useElementGas(int64(len(split)))
 		valueArray := make([]Value, len(split))
 		for index, value := range split {
 			valueArray[index] = toValue_string(value)
@@ -440,12 +456,16 @@ func builtinString_substr(call FunctionCall) Value {
 
 func builtinString_toLowerCase(call FunctionCall) Value {
 	checkObjectCoercible(call.runtime, call.This)
-	return toValue_string(strings.ToLower(call.This.string()))
+	value := call.This.string()
+	call.runtime.useStringGas(len(value))
+	return toValue_string(strings.ToLower(value))
 }
 
 func builtinString_toUpperCase(call FunctionCall) Value {
 	checkObjectCoercible(call.runtime, call.This)
-	return toValue_string(strings.ToUpper(call.This.string()))
+	value := call.This.string()
+	call.runtime.useStringGas(len(value))
+	return toValue_string(strings.ToUpper(value))
 }
 
 // 7.2 Table 2 — Whitespace Characters & 7.3 Table 3 - Line Terminator Characters
@@ -22,6 +22,7 @@ func (in *_runtime) clone() *_runtime {
 		random:     in.random,
 		stackLimit: in.stackLimit,
 		traceLimit: in.traceLimit,
+		location:   in.location,
 	}
 
 	clone := _clone{
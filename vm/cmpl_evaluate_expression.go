@@ -3,24 +3,13 @@ package vm
 import (
 	"fmt"
 	"math"
-	"runtime"
 
 	"github.com/EducationEKT/EKT/vm/token"
 )
 
 func (self *_runtime) cmpl_evaluate_nodeExpression(node _nodeExpression) Value {
-	// Allow interpreter interruption
-	// If the Interrupt channel is nil, then
-	// we avoid runtime.Gosched() overhead (if any)
-	// FIXME: Test this
-	if self.otto.Interrupt != nil {
-		runtime.Gosched()
-		select {
-		case value := <-self.otto.Interrupt:
-			value()
-		default:
-		}
-	}
+	self.pollInterrupt()
+	self.useGas(GAS_EXPRESSION)
 
 	switch node := node.(type) {
 
@@ -181,6 +170,7 @@ func (self *_runtime) cmpl_evaluate_nodeBracketExpression(node *_nodeBracketExpr
 }
 
 func (self *_runtime) cmpl_evaluate_nodeCallExpression(node *_nodeCallExpression, withArgumentList []interface{}) Value {
+	self.useGas(GAS_CALL)
 	rt := self
 	this := Value{}
 	callee := self.cmpl_evaluate_nodeExpression(node.callee)
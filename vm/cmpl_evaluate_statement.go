@@ -2,24 +2,13 @@ package vm
 
 import (
 	"fmt"
-	"runtime"
 
 	"github.com/EducationEKT/EKT/vm/token"
 )
 
 func (self *_runtime) cmpl_evaluate_nodeStatement(node _nodeStatement) Value {
-	// Allow interpreter interruption
-	// If the Interrupt channel is nil, then
-	// we avoid runtime.Gosched() overhead (if any)
-	// FIXME: Test this
-	if self.otto.Interrupt != nil {
-		runtime.Gosched()
-		select {
-		case value := <-self.otto.Interrupt:
-			value()
-		default:
-		}
-	}
+	self.pollInterrupt()
+	self.useGas(GAS_STATEMENT)
 
 	switch node := node.(type) {
 
@@ -145,6 +134,8 @@ func (self *_runtime) cmpl_evaluate_nodeDoWhileStatement(node *_nodeDoWhileState
 	result := emptyValue
 resultBreak:
 	for {
+		self.pollInterrupt()
+		self.useGas(GAS_LOOP)
 		for _, node := range node.body {
 			value := self.cmpl_evaluate_nodeStatement(node)
 			switch value.kind {
@@ -194,6 +185,8 @@ func (self *_runtime) cmpl_evaluate_nodeForInStatement(node *_nodeForInStatement
 	for object != nil {
 		enumerateValue := emptyValue
 		object.enumerate(false, func(name string) bool {
+			self.pollInterrupt()
+			self.useGas(GAS_LOOP)
 			into := self.cmpl_evaluate_nodeExpression(into)
 			// In the case of: for (var abc in def) ...
 			if into.reference() == nil {
@@ -252,6 +245,8 @@ func (self *_runtime) cmpl_evaluate_nodeForStatement(node *_nodeForStatement) Va
 	result := emptyValue
 resultBreak:
 	for {
+		self.pollInterrupt()
+		self.useGas(GAS_LOOP)
 		if test != nil {
 			testResult := self.cmpl_evaluate_nodeExpression(test)
 			testResultValue := testResult.resolve()
@@ -386,6 +381,8 @@ func (self *_runtime) cmpl_evaluate_nodeWhileStatement(node *_nodeWhileStatement
 	result := emptyValue
 resultBreakContinue:
 	for {
+		self.pollInterrupt()
+		self.useGas(GAS_LOOP)
 		if !self.cmpl_evaluate_nodeExpression(test).resolve().bool() {
 			// Stahp: while (false) ...
 			break
@@ -224,6 +224,10 @@ func (rt *_runtime) panicRangeError(argumentList ...interface{}) *_exception {
 func catchPanic(function func()) (err error) {
 	defer func() {
 		if caught := recover(); caught != nil {
+			if caught == OUT_OF_GAS_ERROR || caught == TIMEOUT_ERROR {
+				err = caught.(error)
+				return
+			}
 			if exception, ok := caught.(*_exception); ok {
 				caught = exception.eject()
 			}
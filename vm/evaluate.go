@@ -65,7 +65,10 @@ func (self *_runtime) calculateBinaryExpression(operator token.Token, left Value
 		rightValue = toPrimitive(rightValue)
 
 		if leftValue.IsString() || rightValue.IsString() {
-			return toValue_string(strings.Join([]string{leftValue.string(), rightValue.string()}, ""))
+			// 字符串拼接按照结果的长度计量, 反复拼接可以让长度成倍增长
+			leftString, rightString := leftValue.string(), rightValue.string()
+			self.useStringGas(len(leftString) + len(rightString))
+			return toValue_string(strings.Join([]string{leftString, rightString}, ""))
 		} else {
 			return toValue_float64(leftValue.float64() + rightValue.float64())
 		}
@@ -0,0 +1,73 @@
+package vm
+
+import "errors"
+
+var OUT_OF_GAS_ERROR = errors.New("vm call error: out of gas")
+
+// 合约执行的gas消耗, 按照求值的语句、表达式和调用计量, 与执行时间无关, 所有节点计算的结果一致
+const (
+	GAS_STATEMENT  = 1
+	GAS_EXPRESSION = 1
+	GAS_LOOP       = 1   // 循环每次迭代, 空循环体同样计量
+	GAS_CALL       = 10  // 函数调用
+	GAS_HASH       = 20  // sha3、签名恢复和验证
+	GAS_DB_READ    = 50  // 读取合约存储和MPT
+	GAS_DB_WRITE   = 200 // 写入合约存储和MPT
+	GAS_CONTRACT   = 500 // 调用其他合约, 被调用合约的消耗另外计算
+	GAS_LOG        = 100 // 记录事件日志
+	GAS_COPY       = 1   // 内置函数每处理一个元素或者GAS_COPY_BYTES个字符
+
+	GAS_COPY_BYTES = 32
+)
+
+// 设置合约执行的gas上限, 没有设置时不计量
+func (otto *Otto) SetGasLimit(limit int64) {
+	otto.runtime.metered = true
+	otto.runtime.gasLimit = limit
+	otto.runtime.gasUsed = 0
+	otto.runtime.outOfGas = false
+}
+
+func (otto *Otto) GasUsed() int64 {
+	return otto.runtime.gasUsed
+}
+
+func (otto *Otto) OutOfGas() bool {
+	return otto.runtime.outOfGas
+}
+
+// 超出上限时消耗全部gas并中止执行, 合约中的try/catch无法捕获
+func (self *_runtime) useGas(gas int64) {
+	if !self.metered {
+		return
+	}
+	if self.outOfGas || self.gasUsed+gas > self.gasLimit {
+		self.gasUsed = self.gasLimit
+		self.outOfGas = true
+		panic(OUT_OF_GAS_ERROR)
+	}
+	self.gasUsed += gas
+}
+
+// 内置函数按照处理的元素数量计量, 稀疏数组按照length计算
+func (self *_runtime) useElementGas(count int64) {
+	if count > 0 {
+		self.useGas(count * GAS_COPY)
+	}
+}
+
+// 内置函数按照处理或者生成的字符串长度计量
+func (self *_runtime) useStringGas(length int) {
+	self.useGas(int64(length/GAS_COPY_BYTES+1) * GAS_COPY)
+}
+
+func (self *_runtime) gasRemaining() int64 {
+	return self.gasLimit - self.gasUsed
+}
+
+func (self *_runtime) useChildGas(child *Otto) {
+	if child.OutOfGas() {
+		self.useGas(self.gasRemaining() + 1)
+	}
+	self.useGas(child.GasUsed())
+}
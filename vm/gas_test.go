@@ -0,0 +1,67 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/db"
+)
+
+type testChain struct{}
+
+func (testChain) GetAccount(address []byte) (*types.Account, error) {
+	return types.NewAccount(address), nil
+}
+func (testChain) Author() []byte                            { return nil }
+func (testChain) GetTimestamp() int64                       { return 0 }
+func (testChain) GetHeight() int64                          { return 1 }
+func (testChain) GetParent() []byte                         { return []byte("parent") }
+func (testChain) ModifyContract(address, data []byte) error { return nil }
+
+func TestGasMetering(t *testing.T) {
+	script := `var sum = 0; for (var i = 0; i < 100; i++) { sum += i; }`
+
+	// 相同的代码每次消耗的gas一致
+	vm := NewVM(testChain{}, db.NewMemKVDatabase())
+	vm.SetGasLimit(100000)
+	if _, err := vm.Run(script); err != nil {
+		t.Fatal(err)
+	}
+	used := vm.GasUsed()
+	vm = NewVM(testChain{}, db.NewMemKVDatabase())
+	vm.SetGasLimit(100000)
+	vm.Run(script)
+	if used == 0 || vm.GasUsed() != used {
+		t.Fatal("gas used mismatch", used, vm.GasUsed())
+	}
+
+	// gas不足时中止执行, 合约中的try/catch无法捕获
+	vm = NewVM(testChain{}, db.NewMemKVDatabase())
+	vm.SetGasLimit(used / 2)
+	if _, err := vm.Run(`try { ` + script + ` } catch (e) {}`); err != OUT_OF_GAS_ERROR {
+		t.Fatal("expect out of gas, got", err)
+	}
+	if !vm.OutOfGas() || vm.GasUsed() != used/2 {
+		t.Fatal("gas should be used up", vm.GasUsed())
+	}
+
+	// 空循环体同样计量
+	vm = NewVM(testChain{}, db.NewMemKVDatabase())
+	vm.SetGasLimit(1000)
+	if _, err := vm.Run(`for (;;) {}`); err != OUT_OF_GAS_ERROR {
+		t.Fatal("expect out of gas, got", err)
+	}
+
+	// 内置函数按照处理的元素和字符串长度计量
+	for _, script := range []string{
+		`var a = []; a.length = 4294967295; a.join("")`,
+		`var s = "a"; for (var i = 0; i < 40; i++) { s = s + s; }`,
+		`var s = "a"; for (var i = 0; i < 10; i++) { s = s + s; } s.replace(/a/g, "$'$'$'$'")`,
+	} {
+		vm = NewVM(testChain{}, db.NewMemKVDatabase())
+		vm.SetGasLimit(1000)
+		if _, err := vm.Run(script); err != OUT_OF_GAS_ERROR {
+			t.Fatal("expect out of gas, got", err, script)
+		}
+	}
+}
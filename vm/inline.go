@@ -5941,6 +5941,30 @@ func _newContext(runtime *_runtime) {
 			},
 		}
 
+		emit_function := &_object{
+			runtime:     runtime,
+			class:       "Function",
+			objectClass: _classObject,
+			prototype:   runtime.global.FunctionPrototype,
+			extensible:  true,
+			property: map[string]_property{
+				"length": _property{
+					mode: 0,
+					value: Value{
+						kind:  valueNumber,
+						value: 2,
+					},
+				},
+			},
+			propertyOrder: []string{
+				"length",
+			},
+			value: _nativeFunctionObject{
+				name: "emit",
+				call: builtinAWM_emit,
+			},
+		}
+
 		runtime.global.AWM = &_object{
 			runtime:     runtime,
 			class:       "AWM",
@@ -6018,6 +6042,13 @@ func _newContext(runtime *_runtime) {
 						value: db_delete_function,
 					},
 				},
+				"emit": {
+					mode: 0101,
+					value: Value{
+						kind:  valueObject,
+						value: emit_function,
+					},
+				},
 			},
 			propertyOrder: []string{
 				"sha3_256",
@@ -6030,6 +6061,7 @@ func _newContext(runtime *_runtime) {
 				"db_set",
 				"db_get",
 				"db_delete",
+				"emit",
 			},
 		}
 	}
@@ -16,6 +16,7 @@ import (
 	"github.com/EducationEKT/EKT/crypto"
 	"github.com/EducationEKT/EKT/db"
 	"github.com/EducationEKT/EKT/log"
+	"github.com/EducationEKT/EKT/param"
 	"github.com/EducationEKT/EKT/util"
 	"github.com/EducationEKT/EKT/vm/file"
 	"github.com/EducationEKT/EKT/vm/registry"
@@ -36,6 +37,8 @@ type Otto struct {
 	db        db.IKVDatabase
 	seed      []byte
 	rc        int // random count
+	storage   *contractStorage
+	logs      []userevent.ContractLog
 }
 
 type ContractData struct {
@@ -65,15 +68,23 @@ func NewContractData(contractData *types.ContractData, err error) *ContractData
 }
 
 func (otto *Otto) ContractCall(tx userevent.Transaction, timeout time.Duration) ([]userevent.SubTransaction, []byte, error) {
-	ch := make(chan *ContractCallResp)
+	ch := make(chan *ContractCallResp, 1)
+	otto.Interrupt = make(chan func(), 1)
 	go otto.contractCall(tx, ch)
-	for {
-		select {
-		case <-time.After(timeout):
-			return nil, nil, TIMEOUT_ERROR
-		case result := <-ch:
-			return result.txs, result.data, result.err
-		}
+	select {
+	case <-time.After(timeout):
+		otto.interrupt()
+		return nil, nil, TIMEOUT_ERROR
+	case result := <-ch:
+		return result.txs, result.data, result.err
+	}
+}
+
+// 超时之后中断虚拟机, 执行合约的goroutine在下一个语句或者表达式处退出, 合约中的try/catch无法捕获
+func (otto *Otto) interrupt() {
+	select {
+	case otto.Interrupt <- func() { panic(TIMEOUT_ERROR) }:
+	default:
 	}
 }
 
@@ -104,28 +115,28 @@ func (otto *Otto) contractData() []byte {
 }
 
 func (otto *Otto) UpgradeContract(code []byte, contractData *types.ContractData, timeout time.Duration) (*types.ContractData, error) {
-	ch := make(chan *ContractData)
+	ch := make(chan *ContractData, 1)
+	otto.Interrupt = make(chan func(), 1)
 	go otto.upgradeContract(code, contractData, ch)
-	for {
-		select {
-		case <-time.After(timeout):
-			return nil, TIMEOUT_ERROR
-		case result := <-ch:
-			return result.contractData, result.err
-		}
+	select {
+	case <-time.After(timeout):
+		otto.interrupt()
+		return nil, TIMEOUT_ERROR
+	case result := <-ch:
+		return result.contractData, result.err
 	}
 }
 
 func (otto *Otto) InitContractWithTimeout(code []byte, timeout time.Duration) (*types.ContractData, error) {
-	ch := make(chan *ContractData)
+	ch := make(chan *ContractData, 1)
+	otto.Interrupt = make(chan func(), 1)
 	go otto.initContract(code, ch)
-	for {
-		select {
-		case <-time.After(timeout):
-			return nil, TIMEOUT_ERROR
-		case result := <-ch:
-			return result.contractData, result.err
-		}
+	select {
+	case <-time.After(timeout):
+		otto.interrupt()
+		return nil, TIMEOUT_ERROR
+	case result := <-ch:
+		return result.contractData, result.err
 	}
 }
 
@@ -133,9 +144,14 @@ func (otto *Otto) upgradeContract(code []byte, data *types.ContractData, ch chan
 	_, err := otto.Run(string(code))
 	if err != nil {
 		ch <- NewContractData(nil, err)
+		return
+	}
+	if err = otto.Set("propOld", data.Prop); err == nil {
+		err = otto.Set("contractOldStr", data.Contract)
 	}
-	if otto.Set("propOld", data.Prop) != nil || otto.Set("contractOldStr", data.Contract) != nil {
+	if err != nil {
 		ch <- NewContractData(nil, err)
+		return
 	}
 	_, err = otto.Run(`
 		init();
@@ -145,6 +161,7 @@ func (otto *Otto) upgradeContract(code []byte, data *types.ContractData, ch chan
 	`)
 	if err != nil {
 		ch <- NewContractData(nil, err)
+		return
 	}
 
 	_, err = otto.Run(`
@@ -153,11 +170,13 @@ func (otto *Otto) upgradeContract(code []byte, data *types.ContractData, ch chan
 	`)
 	if err != nil {
 		ch <- NewContractData(nil, err)
+		return
 	}
 
 	value, err := otto.Get("contractData")
 	if err != nil {
 		ch <- NewContractData(nil, err)
+		return
 	}
 	newData := []byte(value.string())
 	var contractData types.ContractData
@@ -169,15 +188,21 @@ func (otto *Otto) initContract(code []byte, ch chan *ContractData) {
 	_, err := otto.Run(string(code))
 	if err != nil {
 		ch <- NewContractData(nil, err)
+		return
 	}
 	_, err = otto.Run(`
 		init();
 		var contractStr = JSON.stringify(contract);
 		var contractData = JSON.stringify({ "prop": prop, "contract": contractStr });
 	`)
+	if err != nil {
+		ch <- NewContractData(nil, err)
+		return
+	}
 	value, err := otto.Get("contractData")
 	if err != nil {
 		ch <- NewContractData(nil, err)
+		return
 	}
 	data := []byte(value.string())
 	var contractData types.ContractData
@@ -193,9 +218,13 @@ func NewVM(chain _interface.VMChain, db db.IKVDatabase) *Otto {
 	}
 	self.runtime.otto = self
 	self.runtime.traceLimit = 10
+	self.storage = newContractStorage(self)
 	self.seed = chain.GetParent()
 	self.chain = chain
 	self.runtime.timestamp = chain.GetTimestamp()
+	if param.IsActive(param.FORK_VM_UTC, chain.GetHeight()) {
+		self.runtime.location = time.UTC
+	}
 	self.Set("console", self.runtime.newConsole())
 
 	self.SetRandomSource(func(vm *Otto) float64 {
@@ -248,7 +277,12 @@ func (otto *Otto) call(tx userevent.Transaction) *ContractCallResp {
 		return NewContractCallResp(nil, nil, err)
 	}
 
-	return NewContractCallResp(subTxs, otto.contractData(), nil)
+	data := otto.contractData()
+	// 序列化合约数据时gas不足, 不能把空的数据当作执行结果
+	if otto.OutOfGas() {
+		return NewContractCallResp(nil, nil, OUT_OF_GAS_ERROR)
+	}
+	return NewContractCallResp(subTxs, data, nil)
 }
 
 func (otto *Otto) LoadContract(addr []byte) error {
@@ -294,8 +328,11 @@ func (otto *Otto) loadContractWithAccount(account types.ContractAccount) error {
 	return err
 }
 
+// 被调用的合约和调用方共享中断, 超时时一起退出
 func (otto *Otto) clone() *Otto {
-	return NewVM(otto.chain, otto.db)
+	vm := NewVM(otto.chain, otto.db)
+	vm.Interrupt = otto.Interrupt
+	return vm
 }
 
 // Run will run the given source (parsing it first if necessary), returning the resulting value and error (if any)
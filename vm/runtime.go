@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/EducationEKT/EKT/vm/ast"
 	"github.com/EducationEKT/EKT/vm/parser"
@@ -60,17 +61,37 @@ type _runtime struct {
 	globalStash  *_objectStash
 	scope        *_scope
 	timestamp    int64
+	location     *time.Location
 	otto         *Otto
 	eval         *_object // The builtin eval, for determine indirect versus direct invocation
 	debugger     func(*Otto)
 	random       func(*Otto) float64
 	stackLimit   int
 	traceLimit   int
+	metered      bool  // 是否计量gas
+	gasLimit     int64 // 合约执行的gas上限
+	gasUsed      int64
+	outOfGas     bool
 
 	labels []string // FIXME
 	lck    sync.Mutex
 }
 
+// Allow interpreter interruption
+// If the Interrupt channel is nil, then
+// we avoid runtime.Gosched() overhead (if any)
+// 语句、表达式和循环的每次迭代都会检查, 空循环体同样可以中断
+func (self *_runtime) pollInterrupt() {
+	if self.otto.Interrupt != nil {
+		runtime.Gosched()
+		select {
+		case value := <-self.otto.Interrupt:
+			value()
+		default:
+		}
+	}
+}
+
 func (self *_runtime) enterScope(scope *_scope) {
 	scope.outer = self.scope
 	if self.scope != nil {
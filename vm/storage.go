@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"encoding/hex"
+	"sort"
+
+	"github.com/EducationEKT/EKT/MPTPlus"
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/param"
+)
+
+// 合约存储, 每个合约一棵MPT, 根节点保存在ContractAccount.StorageRoot中
+// 执行过程中的写入先记录在journal里, 合约执行成功之后由CommitStorage写入MPT, 执行失败时直接丢弃
+type contractStorage struct {
+	otto    *Otto
+	journal map[string]map[string][]byte // 合约地址 -> key -> value, 删除的key对应空的value
+}
+
+func newContractStorage(otto *Otto) *contractStorage {
+	return &contractStorage{otto: otto, journal: make(map[string]map[string][]byte)}
+}
+
+// 合约存储是否使用MPT, 升级之前直接读写数据库
+func (otto *Otto) useStorageTrie() bool {
+	return otto.chain != nil && param.IsActive(param.FORK_CONTRACT_STORAGE, otto.chain.GetHeight())
+}
+
+func (storage *contractStorage) get(address []byte, key string) []byte {
+	if changes, exist := storage.journal[hex.EncodeToString(address)]; exist {
+		if value, exist := changes[key]; exist {
+			return value
+		}
+	}
+	root := storage.root(address)
+	if len(root) == 0 {
+		return nil
+	}
+	value, err := MPTPlus.MTP_Tree(storage.otto.db, root).GetValue(crypto.Sha3_256([]byte(key)))
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+func (storage *contractStorage) set(address []byte, key string, value []byte) {
+	changes, exist := storage.journal[hex.EncodeToString(address)]
+	if !exist {
+		changes = make(map[string][]byte)
+		storage.journal[hex.EncodeToString(address)] = changes
+	}
+	changes[key] = value
+}
+
+func (storage *contractStorage) delete(address []byte, key string) {
+	storage.set(address, key, []byte{})
+}
+
+// 合约执行之前的存储根节点
+func (storage *contractStorage) root(address []byte) []byte {
+	if len(address) != 64 {
+		return nil
+	}
+	account, err := storage.otto.chain.GetAccount(address[:32])
+	if err != nil || account == nil {
+		return nil
+	}
+	contractAccount, exist := account.Contracts[hex.EncodeToString(address[32:])]
+	if !exist {
+		return nil
+	}
+	return contractAccount.StorageRoot
+}
+
+// 把合约执行过程中的写入保存到每个合约的MPT中, 返回合约地址对应的新的根节点
+func (otto *Otto) CommitStorage() (map[string][]byte, error) {
+	roots := make(map[string][]byte)
+	for address, changes := range otto.storage.journal {
+		addr, err := hex.DecodeString(address)
+		if err != nil {
+			return nil, err
+		}
+		trie := MPTPlus.MTP_Tree(otto.db, otto.storage.root(addr))
+		keys := make([]string, 0, len(changes))
+		for key := range changes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := trie.MustInsert(crypto.Sha3_256([]byte(key)), changes[key]); err != nil {
+				return nil, err
+			}
+		}
+		roots[address] = trie.Root
+	}
+	otto.storage.journal = make(map[string]map[string][]byte)
+	return roots, nil
+}
@@ -0,0 +1,66 @@
+package vm
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/EducationEKT/EKT/core/types"
+	"github.com/EducationEKT/EKT/core/userevent"
+	"github.com/EducationEKT/EKT/crypto"
+	"github.com/EducationEKT/EKT/db"
+)
+
+type storageChain struct {
+	testChain
+	account *types.Account
+}
+
+func (chain storageChain) GetAccount(address []byte) (*types.Account, error) {
+	return chain.account, nil
+}
+
+func TestContractStorage(t *testing.T) {
+	kv := db.NewMemKVDatabase()
+	owner, contractAddr := crypto.Sha3_256([]byte("owner")), crypto.Sha3_256([]byte("contract"))
+	chain := storageChain{account: types.NewAccount(owner)}
+	chain.account.Contracts[hex.EncodeToString(contractAddr)] = *types.NewContractAccount(contractAddr, nil, types.ContractData{})
+	tx := userevent.Transaction{To: append(append([]byte{}, owner...), contractAddr...)}
+	get := func(vm *Otto, key string) string {
+		value, err := vm.Run(`AWM.db_get("` + key + `")`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value.String()
+	}
+
+	// 没有提交的写入只在本次执行中可见
+	vm := NewVM(chain, kv)
+	vm.tx = &tx
+	vm.Run(`AWM.db_set("name", "EKT"); AWM.db_set("symbol", "EKT");`)
+	if get(vm, "name") != "EKT" {
+		t.Fatal("journaled value not visible")
+	}
+	discarded := NewVM(chain, kv)
+	discarded.tx = &tx
+	if get(discarded, "name") != "" {
+		t.Fatal("uncommitted value visible")
+	}
+
+	roots, err := vm.CommitStorage()
+	if err != nil || len(roots[hex.EncodeToString(tx.To)]) == 0 {
+		t.Fatal("commit storage failed", err)
+	}
+	contractAccount := chain.account.Contracts[hex.EncodeToString(contractAddr)]
+	contractAccount.StorageRoot = roots[hex.EncodeToString(tx.To)]
+	chain.account.Contracts[hex.EncodeToString(contractAddr)] = contractAccount
+
+	vm = NewVM(chain, kv)
+	vm.tx = &tx
+	if get(vm, "name") != "EKT" {
+		t.Fatal("committed value not found")
+	}
+	vm.Run(`AWM.db_delete("name")`)
+	if get(vm, "name") != "" || get(vm, "symbol") != "EKT" {
+		t.Fatal("delete mismatch")
+	}
+}
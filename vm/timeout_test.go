@@ -0,0 +1,29 @@
+package vm
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/EducationEKT/EKT/db"
+)
+
+// 超时之后执行合约的goroutine需要退出, 合约中的try/catch不能阻止中断
+func TestInterruptOnTimeout(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for _, code := range []string{
+		`var prop = {}; var contract = {}; function init() { while (true) {} }`,
+		`var prop = {}; var contract = {}; function init() { while (true) { try { for (;;) {} } catch (e) {} } }`,
+	} {
+		vm := NewVM(testChain{}, db.NewMemKVDatabase())
+		if _, err := vm.InitContractWithTimeout([]byte(code), 20*time.Millisecond); err != TIMEOUT_ERROR {
+			t.Fatal("expect timeout, got", err)
+		}
+	}
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runtime.NumGoroutine() > before {
+		t.Fatal("contract goroutine is still running")
+	}
+}